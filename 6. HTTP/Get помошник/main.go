@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"net/url"
 	"time"
 )
 
@@ -14,68 +16,191 @@ import (
 type StatusErr struct {
 	Code   int
 	Status string
+	// Header - заголовки ответа
+	Header http.Header
+	// Body - тело ответа, не длиннее maxErrorBody байт
+	Body []byte
+	// Problem - описание ошибки, если ответ пришел
+	// в формате application/problem+json (RFC 7807)
+	Problem *Problem
 }
 
 func (e StatusErr) Error() string {
+	if e.Problem != nil {
+		return "invalid response status: " + e.Status + ": " + e.Problem.Error()
+	}
 	return "invalid response status: " + e.Status
 }
 
 // начало решения
 
+// Unwrap возвращает Problem, чтобы его можно было
+// получить через errors.As
+func (e StatusErr) Unwrap() error {
+	if e.Problem == nil {
+		return nil
+	}
+	return e.Problem
+}
+
+// Problem описывает ошибку в формате application/problem+json (RFC 7807)
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+}
+
+func (p *Problem) Error() string {
+	switch {
+	case p.Title != "" && p.Detail != "":
+		return p.Title + ": " + p.Detail
+	case p.Detail != "":
+		return p.Detail
+	case p.Title != "":
+		return p.Title
+	}
+	return p.Type
+}
+
+// maxErrorBody ограничивает размер тела ответа, сохраняемого в StatusErr
+const maxErrorBody = 64 << 10
+
+// newStatusErr создает ошибку для ответа с HTTP-статусом, отличным от 2xx.
+// Если ответ в формате application/problem+json, разбирает его в Problem.
+func newStatusErr(resp *http.Response, body []byte) StatusErr {
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+
+	err := StatusErr{
+		Code:   resp.StatusCode,
+		Status: resp.Status,
+		Header: resp.Header,
+		Body:   body,
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/problem+json" {
+		var problem Problem
+		if json.Unmarshal(body, &problem) == nil {
+			err.Problem = &problem
+		}
+	}
+
+	return err
+}
+
+// RequestOptions описывает заголовки, URL-параметры
+// и таймаут запроса в миллисекундах
+type RequestOptions struct {
+	Headers map[string]string
+	Params  map[string]string
+	Timeout int
+}
+
 // httpGet выполняет GET-запрос с указанными заголовками и параметрами,
 // парсит ответ как JSON и возвращает получившуюся карту.
 //
 // Считает ошибкой любые ответы с HTTP-статусом, отличным от 2xx.
 func httpGet(uri string, headers map[string]string, params map[string]string, timeout int) (map[string]any, error) {
+	opts := &RequestOptions{Headers: headers, Params: params, Timeout: timeout}
+	return GetJSON[map[string]any](context.Background(), uri, opts)
+}
+
+// GetJSON выполняет GET-запрос с указанными параметрами
+// и декодирует JSON-ответ в значение типа T.
+//
+// Считает ошибкой любые ответы с HTTP-статусом, отличным от 2xx,
+// и возвращает для них StatusErr с телом ответа. Для ответа
+// без тела возвращает нулевое значение.
+func GetJSON[T any](ctx context.Context, uri string, opts *RequestOptions) (T, error) {
+	var result T
+	err := doJSON(ctx, http.MethodGet, uri, nil, opts, &result)
+	return result, err
+}
 
-	client := http.Client{Timeout: time.Duration(timeout * int(time.Millisecond))}
+// PostJSON кодирует body в JSON, отправляет его POST-запросом
+// с указанными параметрами и декодирует JSON-ответ в значение типа Resp.
+//
+// Считает ошибкой любые ответы с HTTP-статусом, отличным от 2xx,
+// и возвращает для них StatusErr с телом ответа. Для ответа
+// без тела возвращает нулевое значение.
+func PostJSON[Req, Resp any](ctx context.Context, uri string, body Req, opts *RequestOptions) (Resp, error) {
+	var result Resp
 
-	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	data, err := json.Marshal(body)
 	if err != nil {
-		panic(err)
+		return result, err
 	}
 
-	// заголовки
-	for header, value := range headers {
-		req.Header.Add(header, value)
+	err = doJSON(ctx, http.MethodPost, uri, data, opts, &result)
+	return result, err
+}
+
+// doJSON выполняет запрос и декодирует JSON-ответ в result
+func doJSON(ctx context.Context, method, uri string, body []byte, opts *RequestOptions, result any) error {
+	if opts == nil {
+		opts = &RequestOptions{}
 	}
 
-	// параметры запроса
-	getParams := url.Values{}
-	for getParameter, value := range params {
-		getParams.Add(getParameter, value)
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(opts.Timeout)*time.Millisecond)
+		defer cancel()
 	}
-	req.URL.RawQuery = getParams.Encode()
 
-	// запрос
-	resp, responseError := client.Do(req)
-	if resp != nil {
-		defer resp.Body.Close()
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, reqBody)
+	if err != nil {
+		return err
 	}
 
-	if resp == nil {
-		return nil, responseError
+	// заголовки
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	for header, value := range opts.Headers {
+		req.Header.Set(header, value)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if responseError != nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return nil, StatusErr{
-			Code:   resp.StatusCode,
-			Status: resp.Status,
+	// параметры запроса
+	if len(opts.Params) > 0 {
+		query := req.URL.Query()
+		for param, value := range opts.Params {
+			query.Set(param, value)
 		}
+		req.URL.RawQuery = query.Encode()
 	}
 
+	// запрос
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer resp.Body.Close()
 
-	var data map[string]any
-	err = json.Unmarshal(body, &data)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return newStatusErr(resp, respBody)
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return data, responseError
+	// у ответов вроде 204 No Content тела нет - result остается нулевым
+	if len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, result)
 }
 
 // конец решения
@@ -130,4 +255,37 @@ func main() {
 		// GET https://httpbingo.org/get
 		// map[id:[42]] <nil>
 	}
+
+	{
+		// Типизированный ответ
+		type slideshow struct {
+			Slideshow struct {
+				Title  string `json:"title"`
+				Slides []struct {
+					Title string `json:"title"`
+				} `json:"slides"`
+			} `json:"slideshow"`
+		}
+		const uri = "https://httpbingo.org/json"
+		data, err := GetJSON[slideshow](context.Background(), uri, &RequestOptions{Timeout: 3000})
+		fmt.Printf("GET %v\n", uri)
+		fmt.Println(data.Slideshow.Title, len(data.Slideshow.Slides), err)
+		fmt.Println()
+		// GET https://httpbingo.org/json
+		// Sample Slide Show 2 <nil>
+	}
+
+	{
+		// POST JSON-документа
+		const uri = "https://httpbingo.org/post"
+		type echo struct {
+			JSON map[string]any `json:"json"`
+		}
+		data, err := PostJSON[map[string]any, echo](context.Background(), uri, map[string]any{"id": 42}, nil)
+		fmt.Printf("POST %v\n", uri)
+		fmt.Println(data.JSON, err)
+		fmt.Println()
+		// POST https://httpbingo.org/post
+		// map[id:42] <nil>
+	}
 }
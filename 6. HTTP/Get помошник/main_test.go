@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// testHandler отвечает JSON и ошибками
+func testHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/array", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[1, 2, 3]`)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "no such item")
+	})
+	mux.HandleFunc("/problem", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"type":"https://example.com/out-of-credit","title":"Out of credit","status":403,"detail":"Balance is 30"}`)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		io.Copy(w, r.Body)
+	})
+
+	return mux
+}
+
+func TestGetJSON(t *testing.T) {
+	server := httptest.NewServer(testHandler())
+	defer server.Close()
+
+	got, err := GetJSON[[]int](context.Background(), server.URL+"/array", nil)
+	if err != nil || !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got %v %v, want [1 2 3]", got, err)
+	}
+
+	if _, err := GetJSON[[]int](context.Background(), "://bad", nil); err == nil {
+		t.Error("bad URL: got nil error")
+	}
+
+	empty, err := GetJSON[map[string]any](context.Background(), server.URL+"/empty", nil)
+	if err != nil || empty != nil {
+		t.Errorf("204: got %v %v, want nil map", empty, err)
+	}
+}
+
+func TestStatusErr(t *testing.T) {
+	server := httptest.NewServer(testHandler())
+	defer server.Close()
+
+	var tests = []struct {
+		path    string
+		code    int
+		body    string
+		problem *Problem
+	}{
+		{
+			path: "/missing",
+			code: http.StatusNotFound,
+			body: "no such item",
+		},
+		{
+			path: "/problem",
+			code: http.StatusForbidden,
+			body: `{"type":"https://example.com/out-of-credit","title":"Out of credit","status":403,"detail":"Balance is 30"}`,
+			problem: &Problem{
+				Type:   "https://example.com/out-of-credit",
+				Title:  "Out of credit",
+				Status: 403,
+				Detail: "Balance is 30",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			_, err := GetJSON[map[string]any](context.Background(), server.URL+test.path, nil)
+
+			var statusErr StatusErr
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("got %v, want StatusErr", err)
+			}
+			if statusErr.Code != test.code || string(statusErr.Body) != test.body {
+				t.Errorf("got %d %q, want %d %q", statusErr.Code, statusErr.Body, test.code, test.body)
+			}
+			if statusErr.Header.Get("Content-Type") == "" {
+				t.Error("got no response headers")
+			}
+			if !reflect.DeepEqual(statusErr.Problem, test.problem) {
+				t.Errorf("got problem %+v, want %+v", statusErr.Problem, test.problem)
+			}
+
+			var problem *Problem
+			if errors.As(err, &problem) != (test.problem != nil) {
+				t.Errorf("errors.As(*Problem): got %v, want %v", problem, test.problem)
+			}
+		})
+	}
+}
+
+func TestPostJSON(t *testing.T) {
+	server := httptest.NewServer(testHandler())
+	defer server.Close()
+
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	got, err := PostJSON[item, item](context.Background(), server.URL+"/echo", item{42, "lamp"}, nil)
+	if err != nil || got != (item{42, "lamp"}) {
+		t.Errorf("got %+v %v, want {ID:42 Name:lamp}", got, err)
+	}
+
+	// ответ без тела дает нулевое значение
+	got, err = PostJSON[item, item](context.Background(), server.URL+"/created", item{42, "lamp"}, nil)
+	if err != nil || got != (item{}) {
+		t.Errorf("201 without body: got %+v %v, want zero item", got, err)
+	}
+
+	// значение, которое нельзя закодировать в JSON
+	_, err = PostJSON[chan int, item](context.Background(), server.URL+"/echo", make(chan int), nil)
+	var typeErr *json.UnsupportedTypeError
+	if !errors.As(err, &typeErr) {
+		t.Errorf("got %v, want json.UnsupportedTypeError", err)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator добавляет к запросу данные для аутентификации
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// invalidator реализуют способы аутентификации,
+// которые умеют сбрасывать отвергнутые сервером учетные данные
+type invalidator interface {
+	Invalidate()
+}
+
+// Auth устанавливает способ аутентификации запросов.
+// Если сервер ответил 401, а способ аутентификации
+// поддерживает сброс токена, запрос повторяется один раз
+// с новым токеном.
+func (h *Handy) Auth(auth Authenticator) *Handy {
+	h.auth = auth
+	return h
+}
+
+// authenticate аутентифицирует запросы и повторяет
+// запрос с новыми учетными данными после ответа 401
+func authenticate(auth Authenticator) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if err := auth.Authenticate(req); err != nil {
+				closeBody(req)
+				return nil, err
+			}
+
+			resp, err := next(req)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+
+			inv, ok := auth.(invalidator)
+			if !ok || !replayable(req) {
+				return resp, err
+			}
+
+			retry := req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return resp, nil
+				}
+				retry.Body = body
+			}
+
+			inv.Invalidate()
+			if err := auth.Authenticate(retry); err != nil {
+				closeBody(retry)
+				return resp, nil
+			}
+
+			discard(resp)
+			return next(retry)
+		}
+	}
+}
+
+// BasicAuth аутентифицирует запросы по логину и паролю
+func BasicAuth(username, password string) Authenticator {
+	return basicAuth{username, password}
+}
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (a basicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+// BearerToken аутентифицирует запросы постоянным токеном
+func BearerToken(token string) Authenticator {
+	return bearerToken(token)
+}
+
+type bearerToken string
+
+func (t bearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// ClientCredentials получает токен OAuth2 по схеме client credentials
+// и аутентифицирует им запросы. Токен кэшируется и обновляется
+// заранее, за ExpiryDelta до истечения срока действия, но не раньше,
+// чем пройдет половина этого срока.
+//
+// Безопасен для одновременного использования из нескольких горутин.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Client - HTTP-клиент для запросов к TokenURL
+	Client *http.Client
+	// ExpiryDelta - за сколько до истечения срока обновлять токен,
+	// но не больше половины срока действия
+	ExpiryDelta time.Duration
+
+	mu    sync.Mutex
+	token string
+	// refresh - когда обновить токен, нулевое - никогда
+	refresh time.Time
+}
+
+// NewClientCredentials создает способ аутентификации OAuth2
+// по схеме client credentials
+func NewClientCredentials(tokenURL, clientID, clientSecret string, scopes ...string) *ClientCredentials {
+	return &ClientCredentials{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		Client:       &http.Client{},
+		ExpiryDelta:  30 * time.Second,
+	}
+}
+
+// Authenticate добавляет к запросу действующий токен
+func (c *ClientCredentials) Authenticate(req *http.Request) error {
+	token, err := c.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Token возвращает действующий токен, при необходимости
+// запрашивая новый у TokenURL
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.refresh.IsZero() || time.Now().Before(c.refresh)) {
+		return c.token, nil
+	}
+
+	token, refresh, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = token
+	c.refresh = refresh
+	return c.token, nil
+}
+
+// Invalidate сбрасывает закэшированный токен,
+// чтобы следующий запрос получил новый
+func (c *ClientCredentials) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = ""
+	c.refresh = time.Time{}
+}
+
+// fetch запрашивает новый токен у TokenURL и возвращает его
+// вместе со временем, когда токен нужно обновить
+func (c *ClientCredentials) fetch(ctx context.Context) (string, time.Time, error) {
+	form := map[string]string{"grant_type": "client_credentials"}
+	if len(c.Scopes) > 0 {
+		form["scope"] = strings.Join(c.Scopes, " ")
+	}
+
+	// RFC 6749 требует кодировать учетные данные перед Basic-аутентификацией
+	resp := NewHandy().
+		Context(ctx).
+		Client(c.Client).
+		URL(c.TokenURL).
+		Auth(BasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))).
+		Header("Accept", "application/json").
+		Form(form).
+		Post()
+	if resp.Err() != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2: %w", resp.Err())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("oauth2: token endpoint returned %d: %s", resp.StatusCode, resp.String())
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	resp.JSON(&token)
+	if resp.Err() != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2: %w", resp.Err())
+	}
+	if token.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("oauth2: token endpoint returned no access_token")
+	}
+
+	var refresh time.Time
+	if token.ExpiresIn > 0 {
+		// короткоживущий токен с запасом в ExpiryDelta
+		// считался бы истекшим сразу после получения
+		lifetime := time.Duration(token.ExpiresIn) * time.Second
+		refresh = time.Now().Add(lifetime - min(c.ExpiryDelta, lifetime/2))
+	}
+	return token.AccessToken, refresh, nil
+}
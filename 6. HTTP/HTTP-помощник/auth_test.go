@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// authHandler обслуживает эндпоинт выдачи токенов /token и ресурс
+// /resource, который принимает только последний выданный токен.
+// issued считает выданные токены.
+func authHandler(expiresIn int, issued *atomic.Int32) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "read write" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":%d}`, n, expiresIn)
+	})
+	mux.HandleFunc("/resource", func(w http.ResponseWriter, r *http.Request) {
+		want := fmt.Sprintf("Bearer token-%d", issued.Load())
+		if r.Header.Get("Authorization") != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	return mux
+}
+
+func TestClientCredentials(t *testing.T) {
+	var issued atomic.Int32
+	server, _ := startServer(t, authHandler(3600, &issued))
+	auth := NewClientCredentials(server.URL+"/token", "client", "s3cret", "read", "write")
+
+	for range 3 {
+		resp := NewHandy().URL(server.URL + "/resource").Auth(auth).Get()
+		if !resp.OK() {
+			t.Fatalf("got %d %v, want 200", resp.StatusCode, resp.Err())
+		}
+	}
+	if got := issued.Load(); got != 1 {
+		t.Errorf("tokens issued: got %d, want 1", got)
+	}
+
+	// сервер отозвал токен - клиент должен получить новый и повторить запрос
+	issued.Add(1)
+	resp := NewHandy().URL(server.URL + "/resource").Auth(auth).Get()
+	if !resp.OK() {
+		t.Fatalf("after revoke: got %d %v, want 200", resp.StatusCode, resp.Err())
+	}
+	if got := issued.Load(); got != 3 {
+		t.Errorf("tokens issued: got %d, want 3", got)
+	}
+}
+
+func TestClientCredentialsRefresh(t *testing.T) {
+	// токены живут меньше ExpiryDelta, поэтому обновляются
+	// по прошествии половины срока действия
+	var tests = []struct {
+		name      string
+		expiresIn int
+		requests  int
+		pause     time.Duration
+		issued    int32
+	}{
+		{"short-lived token is cached", 20, 5, 0, 1},
+		{"refreshed after half of lifetime", 1, 2, 600 * time.Millisecond, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var issued atomic.Int32
+			server, _ := startServer(t, authHandler(test.expiresIn, &issued))
+			auth := NewClientCredentials(server.URL+"/token", "client", "s3cret", "read", "write")
+
+			for range test.requests {
+				resp := NewHandy().URL(server.URL + "/resource").Auth(auth).Get()
+				if !resp.OK() {
+					t.Fatalf("got %d %v, want 200", resp.StatusCode, resp.Err())
+				}
+				time.Sleep(test.pause)
+			}
+			if got := issued.Load(); got != test.issued {
+				t.Errorf("tokens issued: got %d, want %d", got, test.issued)
+			}
+		})
+	}
+}
+
+func TestClientCredentialsConcurrent(t *testing.T) {
+	var issued atomic.Int32
+	server, _ := startServer(t, authHandler(3600, &issued))
+	auth := NewClientCredentials(server.URL+"/token", "client", "s3cret", "read", "write")
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			resp := NewHandy().URL(server.URL + "/resource").Auth(auth).Get()
+			if !resp.OK() {
+				t.Errorf("got %d %v, want 200", resp.StatusCode, resp.Err())
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := issued.Load(); got != 1 {
+		t.Errorf("tokens issued: got %d, want 1", got)
+	}
+}
+
+func TestClientCredentialsBadSecret(t *testing.T) {
+	server, _ := startServer(t, authHandler(3600, new(atomic.Int32)))
+	auth := NewClientCredentials(server.URL+"/token", "client", "wrong", "read", "write")
+
+	resp := NewHandy().URL(server.URL + "/resource").Auth(auth).Get()
+	if resp.Err() == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestAuthFailureClosesBody(t *testing.T) {
+	server, _ := startServer(t, authHandler(3600, new(atomic.Int32)))
+	auth := NewClientCredentials(server.URL+"/token", "client", "wrong", "read", "write")
+
+	file := &closeTracker{Reader: strings.NewReader("data")}
+	resp := NewHandy().URL(server.URL+"/resource").Auth(auth).
+		Multipart(nil, FilePart{Field: "file", Filename: "data.txt", Reader: file}).
+		Post()
+	if resp.Err() == nil {
+		t.Fatal("expected error")
+	}
+
+	// multipart-тело пишется в отдельной горутине, которая закрывает файл
+	deadline := time.Now().Add(time.Second)
+	for !file.closed.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !file.closed.Load() {
+		t.Error("file part was not closed")
+	}
+}
+
+func TestStaticAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	var tests = []struct {
+		auth Authenticator
+		want string
+	}{
+		{BasicAuth("alice", "pass"), "Basic YWxpY2U6cGFzcw=="},
+		{BearerToken("1234567890"), "Bearer 1234567890"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			got := NewHandy().URL(server.URL).Auth(test.auth).Get().String()
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// BatchOption настраивает выполнение Batch
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	failFast bool
+	perHost  int
+}
+
+// FailFast отменяет оставшиеся запросы после первой ошибки.
+// Без этой опции выполняются все запросы, а ошибки собираются вместе.
+func FailFast() BatchOption {
+	return func(c *batchConfig) {
+		c.failFast = true
+	}
+}
+
+// PerHostLimit ограничивает количество одновременных запросов к одному хосту
+func PerHostLimit(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.perHost = n
+	}
+}
+
+// Batch выполняет запросы одновременно, но не больше parallelism за раз,
+// и возвращает ответы в том же порядке, что и запросы.
+//
+// Ошибка - это объединение ошибок всех неудачных запросов,
+// а с опцией FailFast - первая из них.
+func Batch(requests []*Handy, parallelism int, opts ...BatchOption) ([]*HandyResponse, error) {
+	var config batchConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	responses := make([]*HandyResponse, len(requests))
+	errs := make([]error, len(requests))
+	limiter := newHostLimiter(config.perHost)
+
+	var firstErr error
+	var once sync.Once
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(parallelism, len(requests)) {
+		wg.Go(func() {
+			for i := range jobs {
+				responses[i] = runBatched(ctx, requests[i], limiter)
+
+				if err := responses[i].Err(); err != nil {
+					errs[i] = fmt.Errorf("request %d: %w", i, err)
+					if config.failFast {
+						once.Do(func() {
+							firstErr = errs[i]
+							cancel()
+						})
+					}
+				}
+			}
+		})
+	}
+
+	for i := range requests {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if config.failFast {
+		return responses, firstErr
+	}
+	return responses, errors.Join(errs...)
+}
+
+// runBatched выполняет запрос, который отменяется
+// вместе со всем пакетом
+func runBatched(batch context.Context, h *Handy, limiter *hostLimiter) *HandyResponse {
+	if err := batch.Err(); err != nil {
+		return errorResponse(err)
+	}
+
+	ctx, cancel := context.WithCancel(h.ctx)
+	defer cancel()
+	stop := context.AfterFunc(batch, cancel)
+	defer stop()
+
+	uri, _ := h.resolveURL()
+	release, err := limiter.acquire(ctx, uri)
+	if err != nil {
+		return errorResponse(err)
+	}
+	defer release()
+
+	return h.DoContext(ctx, h.requestMethod())
+}
+
+// hostLimiter ограничивает количество одновременных запросов к хосту
+type hostLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, hosts: map[string]chan struct{}{}}
+}
+
+// acquire ждет свободного места для запроса к хосту из uri
+// и возвращает функцию, которая его освобождает
+func (l *hostLimiter) acquire(ctx context.Context, uri string) (func(), error) {
+	if l.limit <= 0 {
+		return func() {}, nil
+	}
+
+	var host string
+	if u, err := url.Parse(uri); err == nil {
+		host = u.Host
+	}
+
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.hosts[host] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
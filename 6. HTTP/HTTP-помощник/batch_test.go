@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchHandler отвечает номером запроса из параметра id.
+// Параметр delay задает задержку ответа в миллисекундах,
+// fail - ответ 500, hang - ожидание отмены запроса.
+func batchHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		delay, _ := strconv.Atoi(query.Get("delay"))
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+
+		switch {
+		case query.Has("fail"):
+			w.WriteHeader(http.StatusInternalServerError)
+		case query.Has("hang"):
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+		w.Write([]byte(query.Get("id")))
+	})
+}
+
+func TestBatchOrder(t *testing.T) {
+	server, _ := startServer(t, batchHandler())
+
+	// поздние запросы отвечают раньше ранних
+	var requests []*Handy
+	for i := range 5 {
+		requests = append(requests, NewHandy().URL(server.URL).
+			Param("id", strconv.Itoa(i)).Param("delay", strconv.Itoa((5-i)*10)))
+	}
+
+	responses, err := Batch(requests, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, resp := range responses {
+		if got := resp.String(); got != strconv.Itoa(i) {
+			t.Errorf("response %d: got %q, want %q", i, got, strconv.Itoa(i))
+		}
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	server, hits := startServer(t, batchHandler())
+
+	var requests []*Handy
+	for i := range 4 {
+		h := NewHandy().URL(server.URL).Param("id", strconv.Itoa(i))
+		if i%2 == 1 {
+			h.Param("fail", "1")
+		}
+		requests = append(requests, h)
+	}
+
+	responses, err := Batch(requests, 2)
+	if hits.Load() != 4 {
+		t.Errorf("hits: got %d, want 4", hits.Load())
+	}
+
+	var statusErr StatusErr
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusInternalServerError {
+		t.Errorf("got %v, want StatusErr 500", err)
+	}
+
+	var tests = []struct {
+		i      int
+		failed bool
+	}{
+		{0, false},
+		{1, true},
+		{2, false},
+		{3, true},
+	}
+
+	for _, test := range tests {
+		t.Run(strconv.Itoa(test.i), func(t *testing.T) {
+			prefix := fmt.Sprintf("request %d: ", test.i)
+			if got := err != nil && strings.Contains(err.Error(), prefix); got != test.failed {
+				t.Errorf("error %q contains %q: got %v, want %v", err, prefix, got, test.failed)
+			}
+			if got := responses[test.i].Err() != nil; got != test.failed {
+				t.Errorf("response error: got %v, want failed %v", responses[test.i].Err(), test.failed)
+			}
+		})
+	}
+}
+
+func TestBatchFailFast(t *testing.T) {
+	server, hits := startServer(t, batchHandler())
+
+	t.Run("cancels running", func(t *testing.T) {
+		requests := []*Handy{
+			NewHandy().URL(server.URL).Param("id", "0").Param("hang", "1"),
+			NewHandy().URL(server.URL).Param("id", "1").Param("fail", "1").Param("delay", "10"),
+			NewHandy().URL(server.URL).Param("id", "2").Param("hang", "1"),
+		}
+
+		start := time.Now()
+		responses, err := Batch(requests, 3, FailFast())
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("got %v, want hanging requests to be cancelled", elapsed)
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), "request 1: ") {
+			t.Errorf("got %v, want error of request 1", err)
+		}
+		for _, i := range []int{0, 2} {
+			if !errors.Is(responses[i].Err(), context.Canceled) {
+				t.Errorf("response %d: got %v, want context.Canceled", i, responses[i].Err())
+			}
+		}
+	})
+
+	t.Run("skips queued", func(t *testing.T) {
+		hits.Store(0)
+		requests := []*Handy{
+			NewHandy().URL(server.URL).Param("id", "0").Param("fail", "1"),
+			NewHandy().URL(server.URL).Param("id", "1"),
+			NewHandy().URL(server.URL).Param("id", "2"),
+		}
+
+		responses, err := Batch(requests, 1, FailFast())
+		if err == nil || !strings.HasPrefix(err.Error(), "request 0: ") {
+			t.Errorf("got %v, want error of request 0", err)
+		}
+		if got := hits.Load(); got != 1 {
+			t.Errorf("hits: got %d, want 1", got)
+		}
+		for _, i := range []int{1, 2} {
+			if !errors.Is(responses[i].Err(), context.Canceled) {
+				t.Errorf("response %d: got %v, want context.Canceled", i, responses[i].Err())
+			}
+		}
+	})
+}
+
+func TestBatchPerHostLimit(t *testing.T) {
+	var mu sync.Mutex
+	active := map[string]int{}
+	peak := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active[r.Host]++
+		peak[r.Host] = max(peak[r.Host], active[r.Host])
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		active[r.Host]--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	// один сервер под двумя именами хоста
+	hosts := []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)}
+
+	var requests []*Handy
+	for i := range 12 {
+		requests = append(requests, NewHandy().URL(hosts[i%2]))
+	}
+
+	if _, err := Batch(requests, 12, PerHostLimit(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(peak) != 2 {
+		t.Fatalf("got hosts %v, want 2", peak)
+	}
+	for host, n := range peak {
+		if n != 2 {
+			t.Errorf("%s: got %d concurrent requests, want 2", host, n)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen возвращается без обращения к серверу,
+// пока цепь для хоста разомкнута
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState - состояние цепи
+type CircuitState int
+
+const (
+	// StateClosed - запросы проходят, неудачи подсчитываются
+	StateClosed CircuitState = iota
+	// StateOpen - запросы сразу завершаются ошибкой ErrCircuitOpen
+	StateOpen
+	// StateHalfOpen - пропускается ограниченное число пробных запросов
+	StateHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// BreakerSettings описывает, когда размыкать и замыкать цепь
+type BreakerSettings struct {
+	// ConsecutiveFailures - сколько неудач подряд размыкают цепь,
+	// 0 - не учитывать
+	ConsecutiveFailures int
+	// FailureRatio - доля неудачных запросов, при которой цепь
+	// размыкается, 0 - не учитывать
+	FailureRatio float64
+	// MinRequests - сколько запросов нужно, чтобы учитывать FailureRatio
+	MinRequests int
+	// Interval - за какой период считается FailureRatio: в замкнутом
+	// состоянии счетчики запросов и неудач сбрасываются каждые Interval,
+	// по умолчанию раз в минуту
+	Interval time.Duration
+	// Cooldown - сколько цепь остается разомкнутой
+	// перед переходом в полуоткрытое состояние
+	Cooldown time.Duration
+	// HalfOpenProbes - сколько пробных запросов пропускается
+	// в полуоткрытом состоянии; столько же успешных замыкают цепь
+	HalfOpenProbes int
+	// IsFailure решает, считать ли результат запроса неудачей.
+	// По умолчанию неудача - ошибка транспорта или статус 5xx.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange вызывается при каждой смене состояния цепи
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+// CircuitBreaker размыкает цепь отдельно для каждого хоста,
+// если запросы к нему слишком часто завершаются неудачей.
+//
+// Безопасен для одновременного использования из нескольких горутин.
+type CircuitBreaker struct {
+	settings BreakerSettings
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit - состояние цепи отдельного хоста
+type circuit struct {
+	state CircuitState
+	// generation меняется при каждой смене состояния, чтобы не учитывать
+	// результаты запросов, начатых в прошлом состоянии
+	generation  int
+	requests    int
+	failures    int
+	consecutive int
+	probes      int
+	openedAt    time.Time
+	// intervalStart - начало периода, за который считаются
+	// requests и failures в замкнутом состоянии
+	intervalStart time.Time
+}
+
+// transition - смена состояния цепи
+type transition struct {
+	host     string
+	from, to CircuitState
+}
+
+// NewCircuitBreaker создает предохранитель с указанными настройками
+func NewCircuitBreaker(settings BreakerSettings) *CircuitBreaker {
+	if settings.HalfOpenProbes <= 0 {
+		settings.HalfOpenProbes = 1
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = isFailure
+	}
+	if settings.Interval <= 0 {
+		settings.Interval = time.Minute
+	}
+
+	return &CircuitBreaker{
+		settings: settings,
+		circuits: map[string]*circuit{},
+	}
+}
+
+// CircuitBreaker пропускает запросы через предохранитель
+func (h *Handy) CircuitBreaker(breaker *CircuitBreaker) *Handy {
+	h.breaker = breaker
+	return h
+}
+
+// State возвращает текущее состояние цепи для хоста
+func (b *CircuitBreaker) State(host string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[host]
+	if !ok {
+		return StateClosed
+	}
+	if c.state == StateOpen && time.Since(c.openedAt) >= b.settings.Cooldown {
+		return StateHalfOpen
+	}
+	return c.state
+}
+
+// breakCircuit не пропускает запросы к хостам с разомкнутой цепью
+// и учитывает результаты остальных запросов
+func breakCircuit(b *CircuitBreaker) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			host := req.URL.Host
+
+			generation, err := b.allow(host)
+			if err != nil {
+				closeBody(req)
+				return nil, err
+			}
+
+			resp, err := next(req)
+			if errors.Is(err, context.Canceled) {
+				b.release(host, generation)
+			} else {
+				b.record(host, generation, b.settings.IsFailure(resp, err))
+			}
+			return resp, err
+		}
+	}
+}
+
+// allow проверяет, можно ли отправить запрос к хосту,
+// и возвращает поколение цепи, в котором он отправлен
+func (b *CircuitBreaker) allow(host string) (int, error) {
+	var changes []transition
+	defer func() { b.notify(changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+	if c.state == StateOpen && time.Since(c.openedAt) >= b.settings.Cooldown {
+		changes = append(changes, b.setState(host, c, StateHalfOpen))
+	}
+
+	switch c.state {
+	case StateOpen:
+		return 0, fmt.Errorf("%s: %w", host, ErrCircuitOpen)
+	case StateHalfOpen:
+		if c.probes >= b.settings.HalfOpenProbes {
+			return 0, fmt.Errorf("%s: %w", host, ErrCircuitOpen)
+		}
+		c.probes++
+	}
+	return c.generation, nil
+}
+
+// record учитывает результат запроса
+func (b *CircuitBreaker) record(host string, generation int, failed bool) {
+	var changes []transition
+	defer func() { b.notify(changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+	if c.generation != generation {
+		return
+	}
+
+	switch c.state {
+	case StateClosed:
+		// доля неудач считается за последний период, иначе после долгой
+		// успешной работы даже полный отказ не достигнет FailureRatio
+		if time.Since(c.intervalStart) >= b.settings.Interval {
+			c.requests, c.failures = 0, 0
+			c.intervalStart = time.Now()
+		}
+		c.requests++
+		if !failed {
+			c.consecutive = 0
+			return
+		}
+		c.failures++
+		c.consecutive++
+
+		s := b.settings
+		tripped := s.ConsecutiveFailures > 0 && c.consecutive >= s.ConsecutiveFailures
+		if s.FailureRatio > 0 && c.requests >= s.MinRequests {
+			tripped = tripped || float64(c.failures)/float64(c.requests) >= s.FailureRatio
+		}
+		if tripped {
+			changes = append(changes, b.setState(host, c, StateOpen))
+		}
+
+	case StateHalfOpen:
+		if failed {
+			changes = append(changes, b.setState(host, c, StateOpen))
+			return
+		}
+		c.requests++
+		if c.requests >= b.settings.HalfOpenProbes {
+			changes = append(changes, b.setState(host, c, StateClosed))
+		}
+	}
+}
+
+// release освобождает место пробного запроса,
+// результат которого не нужно учитывать
+func (b *CircuitBreaker) release(host string, generation int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+	if c.generation == generation && c.state == StateHalfOpen {
+		c.probes--
+	}
+}
+
+// circuit возвращает цепь для хоста, создавая ее при необходимости
+func (b *CircuitBreaker) circuit(host string) *circuit {
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{intervalStart: time.Now()}
+		b.circuits[host] = c
+	}
+	return c
+}
+
+// setState переводит цепь в новое состояние и сбрасывает счетчики
+func (b *CircuitBreaker) setState(host string, c *circuit, state CircuitState) transition {
+	change := transition{host, c.state, state}
+	*c = circuit{state: state, generation: c.generation + 1, intervalStart: time.Now()}
+	if state == StateOpen {
+		c.openedAt = c.intervalStart
+	}
+	return change
+}
+
+// notify сообщает о сменах состояния через OnStateChange
+func (b *CircuitBreaker) notify(changes []transition) {
+	if b.settings.OnStateChange == nil {
+		return
+	}
+	for _, change := range changes {
+		b.settings.OnStateChange(change.host, change.from, change.to)
+	}
+}
+
+// isFailure считает неудачей ошибку транспорта или статус 5xx
+func isFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusHandler отвечает статусом из status
+func statusHandler(status *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	server, hits := startServer(t, statusHandler(&status))
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	var mu sync.Mutex
+	var changes []string
+	breaker := NewCircuitBreaker(BreakerSettings{
+		ConsecutiveFailures: 2,
+		Cooldown:            50 * time.Millisecond,
+		HalfOpenProbes:      1,
+		OnStateChange: func(h string, from, to CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			if h != host {
+				t.Errorf("state change host: got %s, want %s", h, host)
+			}
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	get := func() *HandyResponse {
+		return NewHandy().URL(server.URL).CircuitBreaker(breaker).Get()
+	}
+
+	// две неудачи подряд размыкают цепь
+	get()
+	get()
+	if got := breaker.State(host); got != StateOpen {
+		t.Fatalf("after failures: got %v, want open", got)
+	}
+
+	// разомкнутая цепь не пропускает запросы к серверу
+	if resp := get(); !errors.Is(resp.Err(), ErrCircuitOpen) {
+		t.Errorf("open: got error %v, want ErrCircuitOpen", resp.Err())
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("open: got %d server hits, want 2", got)
+	}
+
+	// неудачный пробный запрос снова размыкает цепь
+	time.Sleep(60 * time.Millisecond)
+	if got := breaker.State(host); got != StateHalfOpen {
+		t.Errorf("after cooldown: got %v, want half-open", got)
+	}
+	get()
+	if got := breaker.State(host); got != StateOpen {
+		t.Errorf("failed probe: got %v, want open", got)
+	}
+
+	// успешный пробный запрос замыкает цепь
+	status.Store(http.StatusOK)
+	time.Sleep(60 * time.Millisecond)
+	if resp := get(); resp.Err() != nil {
+		t.Errorf("probe: got error %v", resp.Err())
+	}
+	if got := breaker.State(host); got != StateClosed {
+		t.Errorf("successful probe: got %v, want closed", got)
+	}
+
+	want := []string{
+		"closed->open",
+		"open->half-open", "half-open->open",
+		"open->half-open", "half-open->closed",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(changes, " ") != strings.Join(want, " ") {
+		t.Errorf("state changes: got %v, want %v", changes, want)
+	}
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	var tests = []struct {
+		name     string
+		interval time.Duration
+		want     CircuitState
+	}{
+		// за интервал учитываются только последние запросы - все неудачные
+		{"interval", 20 * time.Millisecond, StateOpen},
+		// без сброса 5 неудач из 25 запросов не дотягивают до половины
+		{"long interval", time.Hour, StateClosed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var status atomic.Int32
+			status.Store(http.StatusOK)
+			server, _ := startServer(t, statusHandler(&status))
+			host := strings.TrimPrefix(server.URL, "http://")
+
+			breaker := NewCircuitBreaker(BreakerSettings{
+				FailureRatio: 0.5,
+				MinRequests:  5,
+				Interval:     test.interval,
+				Cooldown:     time.Hour,
+			})
+			get := func() {
+				NewHandy().URL(server.URL).CircuitBreaker(breaker).Get()
+			}
+
+			for range 20 {
+				get()
+			}
+			time.Sleep(30 * time.Millisecond)
+
+			status.Store(http.StatusServiceUnavailable)
+			for range 5 {
+				get()
+			}
+
+			if got := breaker.State(host); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerClosesBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(BreakerSettings{ConsecutiveFailures: 1, Cooldown: time.Hour})
+	NewHandy().URL(server.URL).CircuitBreaker(breaker).Get()
+
+	file := &closeTracker{Reader: strings.NewReader("data")}
+	done := make(chan *HandyResponse)
+	go func() {
+		done <- NewHandy().URL(server.URL).CircuitBreaker(breaker).
+			Multipart(nil, FilePart{Field: "file", Filename: "data.txt", Reader: file}).
+			Post()
+	}()
+
+	select {
+	case resp := <-done:
+		if !errors.Is(resp.Err(), ErrCircuitOpen) {
+			t.Errorf("got error %v, want ErrCircuitOpen", resp.Err())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request with open circuit did not return")
+	}
+
+	// multipart-тело пишется в отдельной горутине, которая закрывает файл
+	deadline := time.Now().Add(time.Second)
+	for !file.closed.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !file.closed.Load() {
+		t.Error("file part was not closed")
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse - сохраненный в кэше ответ
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// StoredAt - когда ответ был получен или подтвержден сервером
+	StoredAt time.Time
+	// MaxAge - сколько ответ считается свежим после StoredAt
+	MaxAge time.Duration
+}
+
+// fresh проверяет, можно ли отдать ответ без обращения к серверу
+func (c *CachedResponse) fresh() bool {
+	return time.Since(c.StoredAt) < c.MaxAge
+}
+
+// CacheStore хранит ответы по ключу запроса
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// maxCachedBody - размер тела, больше которого ответ не кэшируется
+const maxCachedBody = 8 << 20
+
+// Cache включает кэширование ответов на GET- и HEAD-запросы
+// в указанном хранилище. Кэш учитывает директивы max-age,
+// no-cache и no-store заголовка Cache-Control, а устаревшие ответы
+// перепроверяет через If-None-Match и If-Modified-Since.
+//
+// Ключ кэша - метод и URL, поэтому запросы с заголовками Authorization
+// или Cookie (в том числе заданными через Auth и CookieJar) и ответы с директивой
+// private не кэшируются: иначе хранилище, общее для нескольких
+// пользователей, отдало бы ответ одного из них другому.
+// Ответы больше 8 МиБ тоже не кэшируются. Заголовок Vary не учитывается.
+func (h *Handy) Cache(store CacheStore) *Handy {
+	h.cache = store
+	return h
+}
+
+// cacheResponses отдает ответы из кэша и сохраняет в него новые.
+// jar - куки, которые клиент добавит к запросу, или nil.
+func cacheResponses(store CacheStore, jar http.CookieJar) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead ||
+				!anonymous(req, jar) {
+				return next(req)
+			}
+
+			key := req.Method + " " + req.URL.String()
+			if cacheControl(req.Header).has("no-store") {
+				store.Delete(key)
+				return next(req)
+			}
+
+			cached, ok := store.Get(key)
+			if ok && cached.fresh() && !cacheControl(req.Header).has("no-cache") {
+				return cached.response(req), nil
+			}
+
+			if ok {
+				if etag := cached.Header.Get("ETag"); etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				if modified := cached.Header.Get("Last-Modified"); modified != "" {
+					req.Header.Set("If-Modified-Since", modified)
+				}
+			}
+
+			resp, err := next(req)
+			if err != nil {
+				return resp, err
+			}
+
+			// ответ не изменился - обновляем срок свежести
+			// и отдаем сохраненное тело
+			if ok && resp.StatusCode == http.StatusNotModified {
+				discard(resp)
+				for k, v := range resp.Header {
+					cached.Header[k] = v
+				}
+				cached.StoredAt = time.Now()
+				cached.MaxAge = maxAge(resp.Header)
+				store.Set(key, cached)
+				return cached.response(req), nil
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return resp, nil
+			}
+
+			if directives := cacheControl(resp.Header); directives.has("no-store") || directives.has("private") {
+				store.Delete(key)
+				return resp, nil
+			}
+
+			// без срока свежести и валидаторов ответ бесполезен для кэша
+			age := maxAge(resp.Header)
+			if age <= 0 && resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+				return resp, nil
+			}
+			if resp.ContentLength > maxCachedBody {
+				return resp, nil
+			}
+
+			// ответ сохраняется, только когда тело прочитано до конца,
+			// поэтому MaxBodySize и Stream работают так же, как без кэша
+			header := resp.Header.Clone()
+			resp.Body = &cachingBody{ReadCloser: resp.Body, store: func(body []byte) {
+				store.Set(key, &CachedResponse{
+					StatusCode: resp.StatusCode,
+					Header:     header,
+					Body:       body,
+					StoredAt:   time.Now(),
+					MaxAge:     age,
+				})
+			}}
+			return resp, nil
+		}
+	}
+}
+
+// cachingBody копит прочитанное тело ответа и передает его в store,
+// когда тело прочитано до конца. Если тело оказалось больше
+// maxCachedBody, копить его перестает.
+type cachingBody struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	store func(body []byte)
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.store == nil {
+		return n, err
+	}
+
+	if b.buf.Len()+n > maxCachedBody {
+		b.buf = bytes.Buffer{}
+		b.store = nil
+		return n, err
+	}
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.store(b.buf.Bytes())
+		b.store = nil
+	}
+	return n, err
+}
+
+// anonymous проверяет, что запрос не несет учетных данных:
+// ответ на такой запрос можно отдать любому пользователю кэша
+func anonymous(req *http.Request, jar http.CookieJar) bool {
+	if req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != "" {
+		return false
+	}
+	return jar == nil || len(jar.Cookies(req.URL)) == 0
+}
+
+// response собирает HTTP-ответ из сохраненного в кэше
+func (c *CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(c.StatusCode) + " " + http.StatusText(c.StatusCode),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
+
+// cacheDirectives - директивы заголовка Cache-Control
+type cacheDirectives map[string]string
+
+// cacheControl разбирает заголовок Cache-Control
+func cacheControl(header http.Header) cacheDirectives {
+	directives := cacheDirectives{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+func (d cacheDirectives) has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// maxAge возвращает срок свежести ответа по заголовку Cache-Control
+func maxAge(header http.Header) time.Duration {
+	directives := cacheControl(header)
+	if directives.has("no-cache") {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(directives["max-age"])
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// MemoryCache хранит ответы в памяти и вытесняет
+// давно не использованные, когда их больше capacity
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryCacheItem struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewMemoryCache создает кэш в памяти на capacity ответов
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get возвращает ответ по ключу
+func (c *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+
+	resp := *elem.Value.(*memoryCacheItem).resp
+	resp.Header = resp.Header.Clone()
+	return &resp, true
+}
+
+// Set сохраняет ответ по ключу
+func (c *MemoryCache) Set(key string, resp *CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*memoryCacheItem).resp = resp
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&memoryCacheItem{key, resp})
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Delete удаляет ответ по ключу
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// KeyValueMap - постоянное хранилище "ключ-значение",
+// например SQLMap из раздела про SQL
+type KeyValueMap interface {
+	Get(key string) (any, error)
+	Set(key string, val any) error
+	Delete(key string) error
+}
+
+// MapCache хранит ответы в постоянном хранилище,
+// кодируя их в JSON
+type MapCache struct {
+	m KeyValueMap
+}
+
+// NewMapCache создает кэш поверх хранилища "ключ-значение"
+func NewMapCache(m KeyValueMap) *MapCache {
+	return &MapCache{m}
+}
+
+// Get возвращает ответ по ключу. Ошибки хранилища
+// считаются отсутствием ответа в кэше.
+func (c *MapCache) Get(key string) (*CachedResponse, bool) {
+	val, err := c.m.Get(key)
+	if err != nil {
+		return nil, false
+	}
+
+	var data []byte
+	switch v := val.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, false
+	}
+
+	var resp CachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// Set сохраняет ответ по ключу
+func (c *MapCache) Set(key string, resp *CachedResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	c.m.Set(key, data)
+}
+
+// Delete удаляет ответ по ключу
+func (c *MapCache) Delete(key string) {
+	c.m.Delete(key)
+}
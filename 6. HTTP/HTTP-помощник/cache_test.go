@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// cacheHandler отвечает с заголовками Cache-Control
+// и номером обращения в теле
+func cacheHandler() http.Handler {
+	var hits atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fresh", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprintf(w, "fresh %d", hits.Add(1))
+	})
+	mux.HandleFunc("/no-store", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store, max-age=60")
+		fmt.Fprintf(w, "no-store %d", hits.Add(1))
+	})
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		fmt.Fprintf(w, "private %d", hits.Add(1))
+	})
+	mux.HandleFunc("/etag", func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprintf(w, "etag %d", n)
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprintf(w, "user %q", r.Header.Get("Authorization"))
+	})
+
+	return mux
+}
+
+func TestCache(t *testing.T) {
+	var tests = []struct {
+		name  string
+		path  string
+		first func(h *Handy)
+		then  func(h *Handy)
+		want  []string
+		hits  int32
+	}{
+		{
+			name: "max-age",
+			path: "/fresh",
+			want: []string{"fresh 1", "fresh 1"},
+			hits: 1,
+		},
+		{
+			name: "request no-cache",
+			path: "/fresh",
+			then: func(h *Handy) { h.Header("Cache-Control", "no-cache") },
+			want: []string{"fresh 1", "fresh 2"},
+			hits: 2,
+		},
+		{
+			name:  "request no-store",
+			path:  "/fresh",
+			first: func(h *Handy) { h.Header("Cache-Control", "no-store") },
+			want:  []string{"fresh 1", "fresh 2"},
+			hits:  2,
+		},
+		{
+			name: "response no-store",
+			path: "/no-store",
+			want: []string{"no-store 1", "no-store 2"},
+			hits: 2,
+		},
+		{
+			name: "private",
+			path: "/private",
+			want: []string{"private 1", "private 2"},
+			hits: 2,
+		},
+		{
+			name: "revalidation",
+			path: "/etag",
+			want: []string{"etag 1", "etag 1"},
+			hits: 2,
+		},
+		{
+			name:  "authorization",
+			path:  "/user",
+			first: func(h *Handy) { h.Auth(BearerToken("alice")) },
+			then:  func(h *Handy) { h.Auth(BearerToken("bob")) },
+			want:  []string{`user "Bearer alice"`, `user "Bearer bob"`},
+			hits:  2,
+		},
+		{
+			name:  "authorization header",
+			path:  "/user",
+			first: func(h *Handy) { h.Header("Authorization", "Bearer alice") },
+			want:  []string{`user "Bearer alice"`, `user ""`},
+			hits:  2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, hits := startServer(t, cacheHandler())
+			cache := NewMemoryCache(10)
+
+			for i, configure := range []func(*Handy){test.first, test.then} {
+				h := NewHandy().URL(server.URL + test.path).Cache(cache)
+				if configure != nil {
+					configure(h)
+				}
+				resp := h.Get()
+				if resp.Err() != nil || resp.String() != test.want[i] {
+					t.Errorf("request %d: got %q %v, want %q", i+1, resp.String(), resp.Err(), test.want[i])
+				}
+			}
+			if got := hits.Load(); got != test.hits {
+				t.Errorf("hits: got %d, want %d", got, test.hits)
+			}
+		})
+	}
+}
+
+func TestCacheCookieJar(t *testing.T) {
+	server, hits := startServer(t, cacheHandler())
+	cache := NewMemoryCache(10)
+
+	// куки добавляет клиент уже после кэша, но кэш должен их учитывать
+	jar, _ := cookiejar.New(nil)
+	u, _ := url.Parse(server.URL)
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "alice"}})
+
+	NewHandy().URL(server.URL + "/fresh").Cache(cache).CookieJar(jar).Get()
+	resp := NewHandy().URL(server.URL + "/fresh").Cache(cache).Get()
+	if got := resp.String(); got != "fresh 2" {
+		t.Errorf("got %q, want %q", got, "fresh 2")
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("hits: got %d, want 2", got)
+	}
+}
+
+func TestCacheBodyLimit(t *testing.T) {
+	// release дописывает тело ответа на запрос с параметром wait
+	release := make(chan struct{})
+	server, hits := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		// без Content-Length: размер известен, только когда тело прочитано
+		io.WriteString(w, "first line\n")
+		w.(http.Flusher).Flush()
+		if r.URL.Query().Has("wait") {
+			select {
+			case <-release:
+			case <-time.After(time.Second):
+			}
+		}
+		io.WriteString(w, strings.Repeat("x", 100))
+	}))
+
+	t.Run("max body size", func(t *testing.T) {
+		hits.Store(0)
+		cache := NewMemoryCache(10)
+
+		resp := NewHandy().URL(server.URL).Cache(cache).MaxBodySize(20).Get()
+		if !errors.Is(resp.Err(), ErrBodyTooLarge) {
+			t.Errorf("got %v, want ErrBodyTooLarge", resp.Err())
+		}
+
+		// недочитанный ответ не сохраняется
+		resp = NewHandy().URL(server.URL).Cache(cache).Get()
+		if resp.Err() != nil || len(resp.Bytes()) != 111 {
+			t.Errorf("got %d bytes %v, want 111", len(resp.Bytes()), resp.Err())
+		}
+		if got := hits.Load(); got != 2 {
+			t.Errorf("hits: got %d, want 2", got)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		hits.Store(0)
+		cache := NewMemoryCache(10)
+
+		// первая строка доступна до того, как сервер допишет тело
+		start := time.Now()
+		resp := NewHandy().URL(server.URL).Param("wait", "1").Cache(cache).Stream().Get()
+		for line := range resp.Lines() {
+			if line != "first line" {
+				t.Errorf("got %q, want %q", line, "first line")
+			}
+			break
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("got first line after %v, want before the body is complete", elapsed)
+		}
+		close(release)
+		resp.Close()
+
+		resp = NewHandy().URL(server.URL).Cache(cache).Stream().Get()
+		io.ReadAll(resp.Body)
+		resp.Close()
+		resp = NewHandy().URL(server.URL).Cache(cache).Get()
+		if resp.Err() != nil || len(resp.Bytes()) != 111 {
+			t.Errorf("got %d bytes %v, want 111", len(resp.Bytes()), resp.Err())
+		}
+		if got := hits.Load(); got != 2 {
+			t.Errorf("hits: got %d, want 2", got)
+		}
+	})
+}
+
+func TestCacheLargeBody(t *testing.T) {
+	server, hits := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		if r.URL.Query().Has("chunked") {
+			w.(http.Flusher).Flush()
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(size))
+		}
+		w.Write(bytes.Repeat([]byte("x"), size))
+	}))
+
+	var tests = []struct {
+		name    string
+		size    int
+		chunked bool
+		stream  bool
+		hits    int32
+	}{
+		{"limit", maxCachedBody, true, false, 1},
+		{"content-length over limit", maxCachedBody + 1, false, false, 2},
+		{"chunked over limit", maxCachedBody + 1, true, false, 2},
+		{"stream over limit", maxCachedBody + 1, true, true, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hits.Store(0)
+			cache := NewMemoryCache(10)
+
+			for range 2 {
+				h := NewHandy().URL(server.URL).Param("size", strconv.Itoa(test.size)).Cache(cache)
+				if test.chunked {
+					h.Param("chunked", "1")
+				}
+				if test.stream {
+					h.Stream()
+				}
+
+				resp := h.Get()
+				var n int
+				if test.stream {
+					written, _ := io.Copy(io.Discard, resp.Body)
+					n = int(written)
+					resp.Close()
+				} else {
+					n = len(resp.Bytes())
+				}
+				if resp.Err() != nil || n != test.size {
+					t.Fatalf("got %d bytes %v, want %d", n, resp.Err(), test.size)
+				}
+			}
+			if got := hits.Load(); got != test.hits {
+				t.Errorf("hits: got %d, want %d", got, test.hits)
+			}
+		})
+	}
+}
+
+// memoryMap - KeyValueMap в памяти
+type memoryMap struct {
+	mu sync.Mutex
+	m  map[string]any
+}
+
+func (m *memoryMap) Get(key string) (any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.m[key]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", key)
+	}
+	return val, nil
+}
+
+func (m *memoryMap) Set(key string, val any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[key] = val
+	return nil
+}
+
+func (m *memoryMap) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.m, key)
+	return nil
+}
+
+func TestMapCache(t *testing.T) {
+	store := &memoryMap{m: map[string]any{}}
+	cache := NewMapCache(store)
+
+	if _, ok := cache.Get("missing"); ok {
+		t.Error("missing: got cached response, want none")
+	}
+
+	want := &CachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {`"v1"`}},
+		Body:       []byte("body"),
+		StoredAt:   time.Now().Truncate(time.Second),
+		MaxAge:     time.Minute,
+	}
+	cache.Set("key", want)
+
+	// хранилище может вернуть как []byte, так и строку
+	for _, val := range []any{store.m["key"], string(store.m["key"].([]byte))} {
+		store.m["key"] = val
+		got, ok := cache.Get("key")
+		if !ok {
+			t.Fatalf("%T: got no cached response", val)
+		}
+		if got.StatusCode != want.StatusCode || got.Header.Get("ETag") != `"v1"` ||
+			string(got.Body) != "body" || !got.StoredAt.Equal(want.StoredAt) || got.MaxAge != want.MaxAge {
+			t.Errorf("%T: got %+v, want %+v", val, got, want)
+		}
+	}
+
+	store.m["bad"] = 42
+	if _, ok := cache.Get("bad"); ok {
+		t.Error("bad value: got cached response, want none")
+	}
+
+	cache.Delete("key")
+	if _, ok := cache.Get("key"); ok {
+		t.Error("deleted: got cached response, want none")
+	}
+
+	// ответы переживают перезапуск: новый MapCache над тем же хранилищем
+	server, hits := startServer(t, cacheHandler())
+	NewHandy().URL(server.URL + "/fresh").Cache(cache).Get()
+	resp := NewHandy().URL(server.URL + "/fresh").Cache(NewMapCache(store)).Get()
+	if got := resp.String(); got != "fresh 1" || hits.Load() != 1 {
+		t.Errorf("got %q after %d hits, want %q after 1", got, hits.Load(), "fresh 1")
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNoInteraction означает, что в кассете нет ответа
+// на запрос, который нужно воспроизвести
+var ErrNoInteraction = errors.New("cassette: no matching interaction")
+
+// CassetteMode - режим работы кассеты
+type CassetteMode int
+
+const (
+	// ModeReplay - ответы берутся из кассеты, сеть не используется
+	ModeReplay CassetteMode = iota
+	// ModeRecord - запросы уходят в сеть, а ответы записываются в кассету
+	ModeRecord
+)
+
+// MatchRules описывает, по каким частям запроса
+// искать ответ в кассете
+type MatchRules struct {
+	Method bool
+	URL    bool
+	// Headers - заголовки, значения которых должны совпадать
+	Headers []string
+	Body    bool
+}
+
+// DefaultMatchRules сравнивает запросы по методу и URL
+func DefaultMatchRules() MatchRules {
+	return MatchRules{Method: true, URL: true}
+}
+
+// redacted заменяет значения скрытых заголовков в кассете
+const redacted = "REDACTED"
+
+// DefaultRedactedHeaders возвращает заголовки запроса
+// с учетными данными, которые не попадают в кассету
+func DefaultRedactedHeaders() []string {
+	return []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+}
+
+// Interaction - записанная пара запрос-ответ
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest - записанный запрос
+type RecordedRequest struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   RecordedBody `json:"body,omitempty"`
+}
+
+// RecordedResponse - записанный ответ
+type RecordedResponse struct {
+	StatusCode int          `json:"status_code"`
+	Header     http.Header  `json:"header,omitempty"`
+	Body       RecordedBody `json:"body,omitempty"`
+}
+
+// RecordedBody хранит тело как строку, если это текст,
+// и как base64 - если двоичные данные
+type RecordedBody []byte
+
+func (b RecordedBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *RecordedBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = []byte(text)
+		return nil
+	}
+
+	var binary struct {
+		Base64 []byte `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &binary); err != nil {
+		return err
+	}
+	*b = binary.Base64
+	return nil
+}
+
+// Cassette - транспорт, который записывает запросы и ответы в файл
+// или воспроизводит их из файла без обращения к сети.
+//
+// Безопасен для одновременного использования из нескольких горутин.
+type Cassette struct {
+	Path  string
+	Mode  CassetteMode
+	Match MatchRules
+	// Redact - заголовки запроса, значения которых заменяются
+	// на "REDACTED", чтобы секреты не попали в файл кассеты.
+	// Сравнение по таким заголовкам в Match.Headers бесполезно.
+	Redact []string
+	// Transport отправляет запросы в режиме записи,
+	// по умолчанию http.DefaultTransport
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewCassette создает кассету для файла path. В режиме воспроизведения
+// сразу загружает записанные взаимодействия.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{
+		Path:      path,
+		Mode:      mode,
+		Match:     DefaultMatchRules(),
+		Redact:    DefaultRedactedHeaders(),
+		Transport: http.DefaultTransport,
+	}
+
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &c.interactions); err != nil {
+			return nil, fmt.Errorf("cassette %s: %w", path, err)
+		}
+		c.used = make([]bool, len(c.interactions))
+	}
+
+	return c, nil
+}
+
+// Client возвращает HTTP-клиента, который работает через кассету.
+// Его можно передать в Handy.Client.
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// RoundTrip записывает или воспроизводит запрос в зависимости от режима
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range c.Redact {
+		if values := recorded.Header.Values(name); len(values) > 0 {
+			recorded.Header[http.CanonicalHeaderKey(name)] = slices.Repeat([]string{redacted}, len(values))
+		}
+	}
+
+	if c.Mode == ModeReplay {
+		return c.replay(req, recorded)
+	}
+
+	// тело исходного запроса уже прочитано, а менять сам запрос
+	// транспорту нельзя, поэтому в сеть уходит копия
+	if recorded.Body != nil {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(recorded.Body))
+	}
+	return c.record(req, recorded)
+}
+
+// Save записывает кассету в файл
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path, data, 0o644)
+}
+
+// record отправляет запрос в сеть и запоминает ответ
+func (c *Cassette) record(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       body,
+		},
+	})
+	c.used = append(c.used, true)
+
+	return resp, nil
+}
+
+// replay ищет ответ на запрос среди записанных. Сначала используются
+// еще не воспроизведенные взаимодействия, затем - последнее подходящее.
+func (c *Cassette) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	found := -1
+	for i, interaction := range c.interactions {
+		if !c.matches(interaction.Request, recorded) {
+			continue
+		}
+		found = i
+		if !c.used[i] {
+			break
+		}
+	}
+
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.URL)
+	}
+	c.used[found] = true
+
+	stored := c.interactions[found].Response
+	return &http.Response{
+		Status:        strconv.Itoa(stored.StatusCode) + " " + http.StatusText(stored.StatusCode),
+		StatusCode:    stored.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        stored.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(stored.Body)),
+		ContentLength: int64(len(stored.Body)),
+		Request:       req,
+	}, nil
+}
+
+// matches сравнивает запросы по правилам кассеты
+func (c *Cassette) matches(stored, req RecordedRequest) bool {
+	if c.Match.Method && stored.Method != req.Method {
+		return false
+	}
+	if c.Match.URL && stored.URL != req.URL {
+		return false
+	}
+	for _, name := range c.Match.Headers {
+		if !slices.Equal(stored.Header.Values(name), req.Header.Values(name)) {
+			return false
+		}
+	}
+	if c.Match.Body && !bytes.Equal(stored.Body, req.Body) {
+		return false
+	}
+	return true
+}
+
+// recordRequest запоминает запрос. Тело запроса прочитывается и закрывается.
+func recordRequest(req *http.Request) (RecordedRequest, error) {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return recorded, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return recorded, err
+	}
+	recorded.Body = body
+
+	return recorded, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Query().Get("id"))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := NewCassette(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2"} {
+		NewHandy().Client(recorder.Client()).URL(server.URL).Param("id", id).Get()
+	}
+	NewHandy().Client(recorder.Client()).URL(server.URL).Param("id", "3").JSON(map[string]int{"n": 3}).Post()
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// после записи сеть больше не нужна
+	server.Close()
+
+	player, err := NewCassette(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player.Match.Body = true
+
+	var tests = []struct {
+		method string
+		id     string
+		want   string
+	}{
+		{http.MethodGet, "2", "GET 2"},
+		{http.MethodGet, "1", "GET 1"},
+		{http.MethodPost, "3", "POST 3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.id, func(t *testing.T) {
+			h := NewHandy().Client(player.Client()).URL(server.URL).Param("id", test.id)
+			if test.method == http.MethodPost {
+				h.JSON(map[string]int{"n": 3})
+			}
+			resp := h.Do(test.method)
+			if resp.Err() != nil || resp.String() != test.want {
+				t.Errorf("got %q %v, want %q", resp.String(), resp.Err(), test.want)
+			}
+		})
+	}
+
+	resp := NewHandy().Client(player.Client()).URL(server.URL).Param("id", "4").Get()
+	if !errors.Is(resp.Err(), ErrNoInteraction) {
+		t.Errorf("got %v, want ErrNoInteraction", resp.Err())
+	}
+}
+
+// TestExamples воспроизводит запросы из main() по записи в testdata
+func TestExamples(t *testing.T) {
+	cassette, err := NewCassette("testdata/httpbingo.json", ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette.Match.Body = true
+	client := cassette.Client()
+
+	{
+		resp := NewHandy().Client(client).URL("https://httpbingo.org/get").Param("id", "42").Get()
+		if !resp.OK() {
+			t.Fatalf("GET: %d %v", resp.StatusCode, resp.Err())
+		}
+
+		var data map[string]any
+		resp.JSON(&data)
+		if got := fmt.Sprint(data["args"]); got != "map[id:[42]]" {
+			t.Errorf("GET args: got %s, want map[id:[42]]", got)
+		}
+	}
+
+	params := map[string]string{
+		"brand":    "lg",
+		"category": "tv",
+	}
+
+	{
+		resp := NewHandy().Client(client).URL("https://httpbingo.org/post").Form(params).Post()
+		var data map[string]any
+		resp.JSON(&data)
+		if got := fmt.Sprint(data["form"]); got != "map[brand:[lg] category:[tv]]" {
+			t.Errorf("POST form: got %s, want map[brand:[lg] category:[tv]]", got)
+		}
+	}
+
+	{
+		resp := NewHandy().Client(client).URL("https://httpbingo.org/post").JSON(params).Post()
+		var data map[string]any
+		resp.JSON(&data)
+		if got := fmt.Sprint(data["json"]); got != "map[brand:lg category:tv]" {
+			t.Errorf("POST JSON: got %s, want map[brand:lg category:tv]", got)
+		}
+	}
+
+	{
+		resp := NewHandy().Client(client).URL("https://httpbingo.org/status/404").Get()
+		var statusErr StatusErr
+		if !errors.As(resp.Err(), &statusErr) || statusErr.Code != http.StatusNotFound {
+			t.Errorf("404: got %v, want StatusErr 404", resp.Err())
+		}
+	}
+}
+
+func TestCassetteRedact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// сервер получает настоящие значения заголовков
+		fmt.Fprint(w, r.Header.Get("Authorization"), r.Header.Get("X-Token"))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := NewCassette(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Redact = append(recorder.Redact, "x-token")
+
+	resp := NewHandy().Client(recorder.Client()).URL(server.URL).
+		Auth(BearerToken("s3cret")).Header("Cookie", "session=s3cret").Header("X-Token", "s3cret").Get()
+	if got := resp.String(); got != "Bearer s3crets3cret" {
+		t.Errorf("got %q, want %q", got, "Bearer s3crets3cret")
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.ReplaceAll(string(data), "Bearer s3crets3cret", ""), "s3cret") {
+		t.Errorf("cassette contains secret:\n%s", data)
+	}
+
+	player, err := NewCassette(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Authorization", "Cookie", "X-Token"} {
+		if got := player.interactions[0].Request.Header.Get(name); got != "REDACTED" {
+			t.Errorf("%s: got %q, want REDACTED", name, got)
+		}
+	}
+}
+
+func TestCassetteKeepsRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	recorder, err := NewCassette(filepath.Join(t.TempDir(), "cassette.json"), ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := &closeTracker{Reader: strings.NewReader("payload")}
+	req, err := http.NewRequest(http.MethodPost, server.URL, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := recorder.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if got, _ := io.ReadAll(resp.Body); string(got) != "payload" {
+		t.Errorf("got %q, want %q", got, "payload")
+	}
+	if req.Body != io.ReadCloser(body) {
+		t.Error("request body was replaced")
+	}
+	if !body.closed.Load() {
+		t.Error("request body was not closed")
+	}
+}
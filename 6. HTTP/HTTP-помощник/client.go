@@ -0,0 +1,67 @@
+package main
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+)
+
+// Client хранит общие настройки запросов: базовый URL, заголовки,
+// таймаут, повторы, авторизацию, промежуточные обработчики и т.п.
+//
+// Client можно использовать одновременно из нескольких горутин:
+// каждый вызов New возвращает независимый запрос, который
+// получает копию настроек и не влияет на другие запросы.
+type Client struct {
+	defaults *Handy
+}
+
+// NewClient создает клиента с настройками из defaults.
+// Клиент хранит копию defaults, поэтому последующие
+// изменения defaults на него не влияют.
+func NewClient(defaults *Handy) *Client {
+	if defaults == nil {
+		defaults = NewHandy()
+	}
+	return &Client{defaults: defaults.Clone()}
+}
+
+// New возвращает новый запрос с настройками клиента
+func (c *Client) New() *Handy {
+	return c.defaults.Clone()
+}
+
+// Clone возвращает копию запроса, которую можно настраивать
+// и выполнять независимо от исходного.
+//
+// Заголовки, параметры, тело и обработчики копируются. HTTP-клиент,
+// авторизация, кеш, предохранитель и хранилище cookie остаются общими -
+// они рассчитаны на одновременное использование. Файлы multipart-запроса
+// тоже общие, поэтому отправить их можно только один раз.
+func (h *Handy) Clone() *Handy {
+	clone := *h
+	clone.headers = maps.Clone(h.headers)
+	clone.pathParams = maps.Clone(h.pathParams)
+
+	params := url.Values{}
+	for k, values := range *h.params {
+		params[k] = slices.Clone(values)
+	}
+	clone.params = &params
+
+	clone.body = slices.Clone(h.body)
+	if h.multipart != nil {
+		multipart := *h.multipart
+		multipart.fields = maps.Clone(h.multipart.fields)
+		multipart.files = slices.Clone(h.multipart.files)
+		clone.multipart = &multipart
+	}
+	if h.retry != nil {
+		retry := *h.retry
+		retry.RetryStatuses = slices.Clone(h.retry.RetryStatuses)
+		clone.retry = &retry
+	}
+	clone.middlewares = slices.Clone(h.middlewares)
+
+	return &clone
+}
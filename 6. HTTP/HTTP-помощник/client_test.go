@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientConcurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RequestURI()+" "+r.Header.Get("X-Client")+" "+r.Header.Get("X-Worker"))
+	}))
+	defer srv.Close()
+
+	client := NewClient(NewHandy().
+		BaseURL(srv.URL).
+		Header("X-Client", "test").
+		Timeout(time.Second).
+		Retry(DefaultRetryPolicy()))
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Go(func() {
+			id := strconv.Itoa(i)
+			resp := client.New().
+				URL("/items/{id}").
+				PathParam("id", id).
+				Param("n", id).
+				Header("X-Worker", id).
+				Get()
+
+			want := "/items/" + id + "?n=" + id + " test " + id
+			if got := resp.String(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestClone(t *testing.T) {
+	h := NewHandy().
+		URL("https://example.com").
+		Header("X-A", "1").
+		Param("p", "1").
+		JSON(map[string]int{"id": 1}).
+		Retry(DefaultRetryPolicy())
+
+	clone := h.Clone().
+		Header("X-A", "2").
+		Param("p", "2").
+		PathParam("id", "2")
+	clone.params.Add("p", "3")
+	clone.body[0] = '['
+	clone.retry.RetryStatuses[0] = 0
+
+	var tests = []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"header", h.headers["X-A"], "1"},
+		{"param", h.params.Encode(), "p=1"},
+		{"path param", len(h.pathParams), 0},
+		{"body", string(h.body), `{"id":1}`},
+		{"retry", h.retry.RetryStatuses[0], http.StatusTooManyRequests},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.got != test.want {
+				t.Errorf("got %v, want %v", test.got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Codec кодирует значения в тело запроса и декодирует тело ответа
+type Codec interface {
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+const (
+	jsonMediaType = "application/json"
+	xmlMediaType  = "application/xml"
+	csvMediaType  = "text/csv"
+	formMediaType = "application/x-www-form-urlencoded"
+)
+
+// codecs - реестр кодеков по типу содержимого
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{m: map[string]Codec{
+	jsonMediaType: JSONCodec{},
+	"text/json":   JSONCodec{},
+	xmlMediaType:  XMLCodec{},
+	"text/xml":    XMLCodec{},
+	csvMediaType:  CSVCodec{},
+	formMediaType: FormCodec{},
+}}
+
+// RegisterCodec регистрирует кодек для типа содержимого,
+// заменяя встроенный, если он был
+func RegisterCodec(mediaType string, codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	codecs.m[strings.ToLower(mediaType)] = codec
+}
+
+// CodecFor возвращает кодек для значения заголовка Content-Type.
+// Типы с суффиксом +json и +xml (например, application/problem+json)
+// обрабатываются кодеками JSON и XML.
+func CodecFor(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	if codec, ok := codecs.m[mediaType]; ok {
+		return codec, true
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		codec, ok := codecs.m[jsonMediaType]
+		return codec, ok
+	case strings.HasSuffix(mediaType, "+xml"):
+		codec, ok := codecs.m[xmlMediaType]
+		return codec, ok
+	}
+	return nil, false
+}
+
+// Body кодирует v кодеком для mediaType и отправляет результат
+// в теле запроса с соответствующим content-type
+func (h *Handy) Body(v any, mediaType string) *Handy {
+	codec, ok := CodecFor(mediaType)
+	if !ok {
+		h.error = fmt.Errorf("no codec for media type %q", mediaType)
+		return h
+	}
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, v); err != nil {
+		h.error = err
+		return h
+	}
+
+	h.headers["Content-Type"] = mediaType
+	h.body = buf.Bytes()
+	h.multipart = nil
+
+	return h
+}
+
+// Decode декодирует тело ответа кодеком, выбранным по заголовку
+// Content-Type, и сохраняет результат по адресу, на который указывает v.
+// Если при декодировании произошла ошибка,
+// она доступна через r.Err().
+func (r *HandyResponse) Decode(v any) {
+	r.decode(r.Header.Get("Content-Type"), v)
+}
+
+// decode декодирует тело ответа кодеком для contentType
+func (r *HandyResponse) decode(contentType string, v any) {
+	codec, ok := CodecFor(contentType)
+	if !ok {
+		r.error = fmt.Errorf("no codec for media type %q", contentType)
+		return
+	}
+
+	body := r.reader()
+	defer body.Close()
+
+	if err := codec.Decode(body, v); err != nil {
+		r.error = err
+	}
+}
+
+// JSONCodec кодирует значения в JSON
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Decode работает как json.Unmarshal: данные после JSON-значения
+// считаются ошибкой
+func (JSONCodec) Decode(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// XMLCodec кодирует значения в XML
+type XMLCodec struct{}
+
+func (XMLCodec) Encode(w io.Writer, v any) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (XMLCodec) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// CSVCodec кодирует таблицы [][]string в CSV
+// и декодирует CSV в *[][]string
+type CSVCodec struct{}
+
+func (CSVCodec) Encode(w io.Writer, v any) error {
+	records, ok := v.([][]string)
+	if !ok {
+		return fmt.Errorf("csv: cannot encode %T, want [][]string", v)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func (CSVCodec) Decode(r io.Reader, v any) error {
+	records, ok := v.(*[][]string)
+	if !ok {
+		return fmt.Errorf("csv: cannot decode into %T, want *[][]string", v)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	var err error
+	*records, err = reader.ReadAll()
+	return err
+}
+
+// FormCodec кодирует url.Values, map[string]string и map[string][]string
+// как application/x-www-form-urlencoded и декодирует в указатели на них
+type FormCodec struct{}
+
+func (FormCodec) Encode(w io.Writer, v any) error {
+	var values url.Values
+	switch form := v.(type) {
+	case url.Values:
+		values = form
+	case map[string][]string:
+		values = form
+	case map[string]string:
+		values = url.Values{}
+		for k, v := range form {
+			values.Add(k, v)
+		}
+	default:
+		return fmt.Errorf("form: cannot encode %T", v)
+	}
+
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
+
+func (FormCodec) Decode(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	switch form := v.(type) {
+	case *url.Values:
+		*form = values
+	case *map[string][]string:
+		*form = values
+	case *map[string]string:
+		*form = map[string]string{}
+		for k := range values {
+			(*form)[k] = values.Get(k)
+		}
+	default:
+		return fmt.Errorf("form: cannot decode into %T", v)
+	}
+	return nil
+}
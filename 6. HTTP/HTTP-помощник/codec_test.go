@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type codecItem struct {
+	XMLName xml.Name `json:"-" xml:"item"`
+	ID      int      `json:"id" xml:"id"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+func TestCodecs(t *testing.T) {
+	var tests = []struct {
+		name  string
+		codec Codec
+		value any
+		// into возвращает указатель, в который декодируется значение
+		into func() any
+	}{
+		{"json", JSONCodec{}, codecItem{ID: 1, Name: "a"}, func() any { return &codecItem{} }},
+		{"xml", XMLCodec{}, codecItem{XMLName: xml.Name{Local: "item"}, ID: 1, Name: "a"}, func() any { return &codecItem{} }},
+		{"csv", CSVCodec{}, [][]string{{"id", "name"}, {"1", "a, b"}}, func() any { return &[][]string{} }},
+		{"form", FormCodec{}, url.Values{"id": {"1", "2"}, "name": {"a b"}}, func() any { return &url.Values{} }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := test.codec.Encode(&buf, test.value); err != nil {
+				t.Fatal(err)
+			}
+
+			got := test.into()
+			if err := test.codec.Decode(&buf, got); err != nil {
+				t.Fatal(err)
+			}
+			if got := reflect.ValueOf(got).Elem().Interface(); !reflect.DeepEqual(got, test.value) {
+				t.Errorf("got %v, want %v", got, test.value)
+			}
+		})
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	var tests = []struct {
+		name  string
+		codec Codec
+		data  string
+		into  any
+	}{
+		{"json trailing data", JSONCodec{}, `{"id":1} garbage`, &codecItem{}},
+		{"json two values", JSONCodec{}, `{"id":1}{"id":2}`, &codecItem{}},
+		{"json syntax", JSONCodec{}, `{"id":`, &codecItem{}},
+		{"xml syntax", XMLCodec{}, `<item><id>1</id>`, &codecItem{}},
+		{"csv target", CSVCodec{}, "a,b\n", &codecItem{}},
+		{"form target", FormCodec{}, "a=1", &codecItem{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.codec.Decode(strings.NewReader(test.data), test.into); err == nil {
+				t.Error("got nil, want error")
+			}
+		})
+	}
+}
+
+func TestCodecFor(t *testing.T) {
+	var tests = []struct {
+		contentType string
+		want        Codec
+	}{
+		{"application/json; charset=utf-8", JSONCodec{}},
+		{"application/problem+json", JSONCodec{}},
+		{"text/xml", XMLCodec{}},
+		{"application/atom+xml", XMLCodec{}},
+		{"text/csv", CSVCodec{}},
+		{"application/x-www-form-urlencoded", FormCodec{}},
+		{"text/plain", nil},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.contentType, func(t *testing.T) {
+			got, _ := CodecFor(test.contentType)
+			if got != test.want {
+				t.Errorf("got %T, want %T", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// отвечаем тем же телом и типом, что пришли в запросе
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	want := codecItem{ID: 42, Name: "answer"}
+	for _, mediaType := range []string{jsonMediaType, xmlMediaType} {
+		t.Run(mediaType, func(t *testing.T) {
+			var got codecItem
+			resp := NewHandy().URL(server.URL).Body(want, mediaType).Post()
+			resp.Decode(&got)
+			if resp.Err() != nil {
+				t.Fatal(resp.Err())
+			}
+			if got.ID != want.ID || got.Name != want.Name {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	resp := NewHandy().URL(server.URL).Header("Content-Type", "text/plain").Post()
+	resp.Decode(&codecItem{})
+	if resp.Err() == nil {
+		t.Error("text/plain: got nil, want error")
+	}
+
+	if resp := NewHandy().Body(want, "text/plain"); resp.error == nil {
+		t.Error("Body: got nil, want error for unknown media type")
+	}
+}
+
+func TestJSONTrailingData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"a":1} garbage`)
+	}))
+	defer server.Close()
+
+	var v map[string]int
+	resp := NewHandy().URL(server.URL).Get()
+	resp.JSON(&v)
+	if resp.Err() == nil {
+		t.Error("got nil, want error")
+	}
+}
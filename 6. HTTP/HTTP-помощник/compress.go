@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Compress включает сжатие тела запроса, заданного через JSON или Form,
+// алгоритмом gzip с заголовком Content-Encoding: gzip.
+// Сервер должен поддерживать сжатые запросы.
+func (h *Handy) Compress() *Handy {
+	h.compress = true
+	return h
+}
+
+// gzipBytes сжимает данные алгоритмом gzip
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress распаковывает тело ответа со сжатием gzip или deflate.
+//
+// http.Transport делает это сам, только если заголовок Accept-Encoding
+// выставил он же. Если его задал вызывающий код, ответ приходит сжатым.
+func decompress(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := next(req)
+		if err != nil || req.Method == http.MethodHead {
+			return resp, err
+		}
+
+		var newReader func(io.Reader) (io.Reader, error)
+		switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+		case "gzip", "x-gzip":
+			newReader = func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			}
+		case "deflate":
+			newReader = newDeflateReader
+		default:
+			return resp, nil
+		}
+
+		resp.Body = &decodedBody{body: resp.Body, newReader: newReader}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+		return resp, nil
+	}
+}
+
+// newDeflateReader распаковывает deflate. По стандарту это поток zlib,
+// но некоторые серверы присылают deflate без zlib-заголовка.
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	// первый байт zlib - метод сжатия 8, а первые два байта кратны 31
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// decodedBody распаковывает тело ответа по мере чтения.
+// Распаковщик создается при первом чтении, поэтому пустое
+// тело не считается ошибкой.
+type decodedBody struct {
+	body      io.ReadCloser
+	newReader func(io.Reader) (io.Reader, error)
+	reader    io.Reader
+	err       error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = b.newReader(b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	return b.body.Close()
+}
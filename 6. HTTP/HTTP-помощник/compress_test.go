@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDecompress(t *testing.T) {
+	const text = "hello, compressed world"
+
+	// сжатые тела для ?encoding=gzip, zlib и raw
+	compressed := map[string][]byte{}
+	for name, newWriter := range map[string]func(io.Writer) io.WriteCloser{
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"zlib": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"raw": func(w io.Writer) io.WriteCloser {
+			writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return writer
+		},
+	} {
+		var buf bytes.Buffer
+		writer := newWriter(&buf)
+		writer.Write([]byte(text))
+		writer.Close()
+		compressed[name] = buf.Bytes()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.URL.Query().Get("encoding")
+		if encoding == "zlib" || encoding == "raw" {
+			w.Header().Set("Content-Encoding", "deflate")
+		} else {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		w.Write(compressed[encoding])
+	}))
+	defer server.Close()
+
+	for _, encoding := range []string{"gzip", "zlib", "raw"} {
+		t.Run(encoding, func(t *testing.T) {
+			// Accept-Encoding задан вызывающим кодом, поэтому
+			// http.Transport не распаковывает ответ сам
+			resp := NewHandy().URL(server.URL).Param("encoding", encoding).
+				Header("Accept-Encoding", "gzip, deflate").Get()
+			if resp.Err() != nil || resp.String() != text {
+				t.Errorf("got %q %v, want %q", resp.String(), resp.Err(), text)
+			}
+			if got := resp.Header.Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding: got %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestCompressRetry(t *testing.T) {
+	const text = `{"id":1}`
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil || r.Header.Get("Content-Encoding") != "gzip" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(reader)
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.NonIdempotent = true
+
+	// при повторе уходит то же сжатое тело
+	resp := NewHandy().URL(server.URL).JSON(map[string]int{"id": 1}).Compress().Retry(policy).Post()
+	if resp.Err() != nil || resp.String() != text {
+		t.Errorf("got %q %v, want %q", resp.String(), resp.Err(), text)
+	}
+	if resp.Attempts != 2 {
+		t.Errorf("attempts: got %d, want 2", resp.Attempts)
+	}
+}
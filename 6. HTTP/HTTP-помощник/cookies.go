@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CookieJar хранит cookie по правилам net/http/cookiejar
+// и умеет сохранять их в файл, чтобы сессия переживала перезапуск.
+//
+// Просроченные cookie не сохраняются и не загружаются,
+// а secure-cookie после загрузки по-прежнему отправляются только по HTTPS.
+// Безопасен для одновременного использования из нескольких горутин.
+type CookieJar struct {
+	path string
+
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	entries map[string]savedCookie
+}
+
+// savedCookie - cookie вместе с URL, с которого она пришла
+type savedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// NewCookieJar создает хранилище cookie для файла path
+// и загружает из него сохраненную сессию, если файл существует.
+// Если path пустой, cookie хранятся только в памяти.
+func NewCookieJar(path string) (*CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &CookieJar{
+		path:    path,
+		jar:     jar,
+		entries: map[string]savedCookie{},
+	}
+
+	if path == "" {
+		return j, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []savedCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range saved {
+		u, err := url.Parse(entry.URL)
+		if err != nil || entry.Cookie == nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{entry.Cookie})
+	}
+
+	return j, nil
+}
+
+// CookieJar устанавливает хранилище cookie для запроса,
+// например созданное через NewCookieJar
+func (h *Handy) CookieJar(jar http.CookieJar) *Handy {
+	h.jar = jar
+	return h
+}
+
+// SetCookies сохраняет cookie, полученные в ответе с адреса u
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	for _, c := range cookies {
+		c := *c
+		key := cookieKey(u, &c)
+
+		// Max-Age важнее Expires и отсчитывается от момента получения
+		switch {
+		case c.MaxAge < 0:
+			delete(j.entries, key)
+			continue
+		case c.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+			c.MaxAge = 0
+		}
+
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			delete(j.entries, key)
+			continue
+		}
+
+		origin := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+		j.entries[key] = savedCookie{URL: origin.String(), Cookie: &c}
+	}
+}
+
+// Cookies возвращает cookie, которые нужно отправить на адрес u
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save записывает непросроченные cookie в файл.
+// Файл заменяется целиком, чтобы при сбое не остался обрезанным.
+func (j *CookieJar) Save() error {
+	if j.path == "" {
+		return errors.New("cookie jar has no file")
+	}
+
+	j.mu.Lock()
+	now := time.Now()
+	saved := make([]savedCookie, 0, len(j.entries))
+	for key, entry := range j.entries {
+		if !entry.Cookie.Expires.IsZero() && !entry.Cookie.Expires.After(now) {
+			delete(j.entries, key)
+			continue
+		}
+		saved = append(saved, entry)
+	}
+	j.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// CreateTemp создает файл с правами 0600: cookie сессии - секрет
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
+
+// cookieKey возвращает ключ cookie по домену, пути и имени -
+// так же, как их различает net/http/cookiejar
+func cookieKey(u *url.URL, c *http.Cookie) string {
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	if domain == "" {
+		domain = strings.ToLower(u.Hostname())
+	}
+
+	path := c.Path
+	if path == "" || path[0] != '/' {
+		path = defaultCookiePath(u.Path)
+	}
+
+	return domain + ";" + path + ";" + c.Name
+}
+
+// defaultCookiePath возвращает путь cookie по умолчанию (RFC 6265, 5.1.4)
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieJarSaveLoad(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "42", MaxAge: 3600})
+			http.SetCookie(w, &http.Cookie{Name: "old", Value: "1", Expires: time.Now().Add(-time.Hour)})
+			return
+		}
+		var names []string
+		for _, c := range r.Cookies() {
+			names = append(names, c.Name)
+		}
+		slices.Sort(names)
+		w.Write([]byte(strings.Join(names, ",")))
+	}))
+	defer server.Close()
+
+	// cookiejar считает loopback-адреса защищенными и отправляет на них
+	// secure-cookie даже по HTTP, поэтому сервер доступен как shop.example
+	dialer := &net.Dialer{}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+	base := "http://shop.example"
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	jar, err := NewCookieJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := NewHandy().Client(client).URL(base + "/login").CookieJar(jar).Get(); resp.Err() != nil {
+		t.Fatal(resp.Err())
+	}
+
+	// cookie, которая истечет до сохранения, и secure-cookie с HTTPS-адреса
+	u, _ := url.Parse(base)
+	jar.SetCookies(u, []*http.Cookie{{Name: "short", Value: "1", Expires: time.Now().Add(50 * time.Millisecond)}})
+	secure, _ := url.Parse("https://shop.example/")
+	jar.SetCookies(secure, []*http.Cookie{{Name: "secure", Value: "1", Secure: true}})
+
+	time.Sleep(100 * time.Millisecond)
+	if err := jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewCookieJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		url  string
+		want string
+	}{
+		{base + "/", "session"},
+		{"https://shop.example/", "secure,session"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			u, _ := url.Parse(test.url)
+			var names []string
+			for _, c := range loaded.Cookies(u) {
+				names = append(names, c.Name)
+			}
+			slices.Sort(names)
+			if got := strings.Join(names, ","); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	// secure-cookie не уходит по http://
+	resp := NewHandy().Client(client).URL(base + "/echo").CookieJar(loaded).Get()
+	if got := resp.String(); got != "session" {
+		t.Errorf("sent cookies: got %q, want %q", got, "session")
+	}
+
+	// Max-Age сохраняется как срок действия, отсчитанный от получения cookie
+	for _, entry := range loaded.entries {
+		if entry.Cookie.Name != "session" {
+			continue
+		}
+		if left := time.Until(entry.Cookie.Expires); left < 59*time.Minute || left > time.Hour {
+			t.Errorf("session expires in %v, want about 1h", left)
+		}
+	}
+}
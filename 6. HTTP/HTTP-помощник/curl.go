@@ -0,0 +1,443 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Curl возвращает команду curl, которая отправляет тот же запрос:
+// метод, URL с параметрами, заголовки и тело.
+//
+// Содержимое файлов multipart-запроса не выводится - вместо него
+// указывается имя файла. Тело, сжатое через Compress, выводится несжатым.
+func (h *Handy) Curl() string {
+	uri, err := h.requestURL()
+	if err != nil {
+		uri = h.url
+	}
+
+	method := h.requestMethod()
+	hasBody := h.body != nil || h.multipart != nil
+
+	args := []string{"curl"}
+	if !(method == http.MethodGet && !hasBody) && !(method == http.MethodPost && hasBody) {
+		if method == http.MethodHead {
+			args = append(args, "--head")
+		} else {
+			args = append(args, "-X", method)
+		}
+	}
+	args = append(args, shellQuote(uri))
+
+	for _, name := range slices.Sorted(maps.Keys(h.headers)) {
+		// границу multipart curl выбирает сам
+		if h.multipart != nil && strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		args = append(args, "-H", shellQuote(name+": "+h.headers[name]))
+	}
+
+	switch auth := h.auth.(type) {
+	case basicAuth:
+		args = append(args, "-u", shellQuote(auth.username+":"+auth.password))
+	case bearerToken:
+		args = append(args, "-H", shellQuote("Authorization: Bearer "+string(auth)))
+	}
+
+	switch {
+	case h.multipart != nil:
+		for _, name := range slices.Sorted(maps.Keys(h.multipart.fields)) {
+			args = append(args, "--form-string", shellQuote(name+"="+h.multipart.fields[name]))
+		}
+		for _, file := range h.multipart.files {
+			part := file.Field + "=@" + file.Filename
+			if file.ContentType != "" {
+				part += ";type=" + file.ContentType
+			}
+			args = append(args, "-F", shellQuote(part))
+		}
+	case h.body != nil:
+		args = append(args, "--data-binary", shellQuote(string(h.body)))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// shellQuote заключает строку в одинарные кавычки для POSIX-шелла
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@%+=,", r)
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ParseCurl разбирает команду curl и возвращает настроенный запрос.
+// Метод запроса сохраняется через Method, поэтому выполнить его
+// можно через Send.
+//
+// Поддерживаются флаги -X, -H, -d и его варианты, --data-urlencode,
+// -F, --form-string, -u, -G, -I, -A, -e, -b, -m и --compressed.
+// Флаги, которые не меняют запрос (-s, -L, -k, -v и подобные),
+// пропускаются, а неизвестные считаются ошибкой.
+func ParseCurl(cmd string) (*Handy, error) {
+	args, err := splitShell(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	p := curlParser{h: NewHandy(), fields: map[string]string{}}
+	if err := p.parse(args); err != nil {
+		return nil, err
+	}
+	return p.build()
+}
+
+// curlFlagsWithValue - флаги curl, за которыми следует значение
+var curlFlagsWithValue = map[string]bool{
+	"-X": true, "--request": true,
+	"-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-raw": true, "--data-ascii": true, "--data-binary": true,
+	"--data-urlencode": true, "--url": true,
+	"-F": true, "--form": true, "--form-string": true,
+	"-u": true, "--user": true,
+	"-A": true, "--user-agent": true,
+	"-e": true, "--referer": true,
+	"-b": true, "--cookie": true,
+	"-m": true, "--max-time": true,
+	"-o": true, "--output": true, "--connect-timeout": true,
+}
+
+// curlIgnoredFlags - флаги curl, которые не меняют запрос
+var curlIgnoredFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-L": true, "--location": true, "-k": true, "--insecure": true,
+	"-v": true, "--verbose": true, "-i": true, "--include": true,
+	"-f": true, "--fail": true, "-#": true, "--progress-bar": true,
+	"--http1.1": true, "--http2": true,
+}
+
+// curlParser накапливает части запроса при разборе команды curl
+type curlParser struct {
+	h       *Handy
+	uri     string
+	method  string
+	data    []string
+	get     bool
+	head    bool
+	fields  map[string]string
+	files   []FilePart
+	hasForm bool
+}
+
+// parse разбирает аргументы команды
+func (p *curlParser) parse(args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if p.uri != "" {
+				return fmt.Errorf("curl: unexpected argument %q", arg)
+			}
+			p.uri = arg
+			continue
+		}
+
+		flag, value, hasValue := arg, "", false
+		switch {
+		case strings.HasPrefix(arg, "--"):
+		case len(arg) > 2 && curlFlagsWithValue[arg[:2]]:
+			// значение короткого флага может идти слитно: -XPOST
+			flag, value, hasValue = arg[:2], arg[2:], true
+		case len(arg) > 2:
+			// несколько коротких флагов вместе: -sSL
+			for _, c := range arg[1:] {
+				if err := p.apply("-"+string(c), ""); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if curlFlagsWithValue[flag] && !hasValue {
+			i++
+			if i >= len(args) {
+				return fmt.Errorf("curl: %s requires a value", flag)
+			}
+			value = args[i]
+		}
+
+		if err := p.apply(flag, value); err != nil {
+			return err
+		}
+	}
+
+	if p.uri == "" {
+		return errors.New("curl: no URL specified")
+	}
+	return nil
+}
+
+// apply применяет один флаг
+func (p *curlParser) apply(flag, value string) error {
+	switch flag {
+	case "-X", "--request":
+		p.method = strings.ToUpper(value)
+	case "-H", "--header":
+		name, val, ok := strings.Cut(value, ":")
+		if !ok {
+			return fmt.Errorf("curl: invalid header %q", value)
+		}
+		p.h.Header(strings.TrimSpace(name), strings.TrimSpace(val))
+	case "-d", "--data", "--data-ascii", "--data-binary":
+		if strings.HasPrefix(value, "@") {
+			data, err := os.ReadFile(value[1:])
+			if err != nil {
+				return fmt.Errorf("curl: %w", err)
+			}
+			value = string(data)
+			if flag != "--data-binary" {
+				value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+			}
+		}
+		p.data = append(p.data, value)
+	case "--data-raw":
+		p.data = append(p.data, value)
+	case "--data-urlencode":
+		encoded, err := curlURLEncode(value)
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, encoded)
+	case "-F", "--form":
+		return p.form(value)
+	case "--form-string":
+		name, val, _ := strings.Cut(value, "=")
+		p.fields[name] = val
+		p.hasForm = true
+	case "-u", "--user":
+		username, password, _ := strings.Cut(value, ":")
+		p.h.Auth(BasicAuth(username, password))
+	case "-A", "--user-agent":
+		p.h.Header("User-Agent", value)
+	case "-e", "--referer":
+		p.h.Header("Referer", value)
+	case "-b", "--cookie":
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("curl: cookie files are not supported: %q", value)
+		}
+		p.h.Header("Cookie", value)
+	case "-m", "--max-time":
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("curl: invalid max time %q", value)
+		}
+		p.h.Timeout(time.Duration(seconds * float64(time.Second)))
+	case "--compressed":
+		p.h.Header("Accept-Encoding", "gzip, deflate")
+	case "-G", "--get":
+		p.get = true
+	case "-I", "--head":
+		p.head = true
+	case "--url":
+		p.uri = value
+	case "-o", "--output", "--connect-timeout":
+	default:
+		if !curlIgnoredFlags[flag] {
+			return fmt.Errorf("curl: unsupported flag %s", flag)
+		}
+	}
+	return nil
+}
+
+// form разбирает значение -F: name=value, name=@file или name=<file
+func (p *curlParser) form(value string) error {
+	p.hasForm = true
+
+	name, val, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("curl: invalid form field %q", value)
+	}
+
+	switch {
+	case strings.HasPrefix(val, "@"):
+		path, params, _ := strings.Cut(val[1:], ";")
+		file := FilePart{Field: name, Filename: filepath.Base(path), Reader: &lazyFile{path: path}}
+		for _, param := range strings.Split(params, ";") {
+			key, v, _ := strings.Cut(param, "=")
+			switch key {
+			case "type":
+				file.ContentType = v
+			case "filename":
+				file.Filename = strings.Trim(v, `"`)
+			}
+		}
+		p.files = append(p.files, file)
+	case strings.HasPrefix(val, "<"):
+		data, err := os.ReadFile(val[1:])
+		if err != nil {
+			return fmt.Errorf("curl: %w", err)
+		}
+		p.fields[name] = string(data)
+	default:
+		p.fields[name] = val
+	}
+	return nil
+}
+
+// build собирает запрос из разобранных частей
+func (p *curlParser) build() (*Handy, error) {
+	if p.hasForm && len(p.data) > 0 {
+		return nil, errors.New("curl: -d and -F cannot be combined")
+	}
+
+	uri := p.uri
+	if !strings.Contains(uri, "://") {
+		uri = "http://" + uri
+	}
+
+	method := http.MethodGet
+	data := strings.Join(p.data, "&")
+	switch {
+	case p.head:
+		method = http.MethodHead
+	case p.get:
+		if data != "" {
+			if strings.Contains(uri, "?") {
+				uri += "&" + data
+			} else {
+				uri += "?" + data
+			}
+		}
+	case p.hasForm:
+		method = http.MethodPost
+		p.h.Multipart(p.fields, p.files...)
+	case len(p.data) > 0:
+		method = http.MethodPost
+		p.h.body = []byte(data)
+		if _, ok := p.h.headers["Content-Type"]; !ok {
+			p.h.headers["Content-Type"] = formMediaType
+		}
+	}
+
+	if p.method != "" {
+		method = p.method
+	}
+
+	return p.h.URL(uri).Method(method), nil
+}
+
+// curlURLEncode кодирует значение --data-urlencode:
+// content, =content, name=content, @file или name@file
+func curlURLEncode(value string) (string, error) {
+	if i := strings.IndexAny(value, "=@"); i >= 0 {
+		name, rest := value[:i], value[i+1:]
+		if value[i] == '@' {
+			data, err := os.ReadFile(rest)
+			if err != nil {
+				return "", fmt.Errorf("curl: %w", err)
+			}
+			rest = string(data)
+		}
+		if name == "" {
+			return url.QueryEscape(rest), nil
+		}
+		return name + "=" + url.QueryEscape(rest), nil
+	}
+	return url.QueryEscape(value), nil
+}
+
+// splitShell разбивает командную строку на аргументы
+// по правилам POSIX-шелла: кавычки, экранирование и перенос строки
+func splitShell(cmd string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case c == '\\' && i+1 < len(cmd):
+			i++
+			if cmd[i] != '\n' {
+				current.WriteByte(cmd[i])
+				inArg = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(cmd[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("curl: unterminated single quote")
+			}
+			current.WriteString(cmd[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(cmd) && cmd[i] != '"'; i++ {
+				if cmd[i] == '\\' && i+1 < len(cmd) && strings.IndexByte("\"\\$`\n", cmd[i+1]) >= 0 {
+					i++
+					if cmd[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteByte(cmd[i])
+			}
+			if i >= len(cmd) {
+				return nil, errors.New("curl: unterminated double quote")
+			}
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// lazyFile открывает файл при первом чтении,
+// чтобы ParseCurl не держал открытыми файлы неотправленных запросов
+type lazyFile struct {
+	path string
+	file *os.File
+}
+
+func (f *lazyFile) Read(p []byte) (int, error) {
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+	}
+	return f.file.Read(p)
+}
+
+func (f *lazyFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCurl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		user, pass, _ := r.BasicAuth()
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Content-Type")+" "+user+":"+pass+" "+string(body))
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		name string
+		cmd  string
+		want string
+	}{
+		{"get", `curl ` + srv.URL + `/get?id=42`, "GET /get?id=42  : "},
+		{"data", `curl -d a=1 -d 'b=2' ` + srv.URL, "POST / application/x-www-form-urlencoded : a=1&b=2"},
+		{"method", `curl -XPUT ` + srv.URL + ` -H 'Content-Type: application/json' --data-raw '{"id":42}'`, `PUT / application/json : {"id":42}`},
+		{"header case", `curl -H 'content-type: text/plain' -d x ` + srv.URL, "POST / text/plain : x"},
+		{"urlencode", `curl -G ` + srv.URL + ` --data-urlencode "q=hello world"`, "GET /?q=hello+world  : "},
+		{"user", `curl -sSL -u bob:secret ` + srv.URL, "GET /  bob:secret "},
+		{"quotes", `curl ` + srv.URL + ` \
+  --data-binary 'it'\''s'`, "POST / application/x-www-form-urlencoded : it's"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := ParseCurl(test.cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := h.Send().String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+
+			// команда из Curl отправляет тот же запрос
+			h, err = ParseCurl(h.Curl())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := h.Send().String(); got != test.want {
+				t.Errorf("round trip: got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCurlErrors(t *testing.T) {
+	var tests = []string{
+		`curl`,
+		`curl --unknown https://example.com`,
+		`curl -H https://example.com`,
+		`curl 'https://example.com`,
+	}
+
+	for _, cmd := range tests {
+		if _, err := ParseCurl(cmd); err == nil {
+			t.Errorf("%s: got nil, want error", cmd)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksumMismatch означает, что контрольная сумма
+// скачанного файла не совпала с ожидаемой
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// DownloadOption настраивает выполнение Download
+type DownloadOption func(*downloadConfig)
+
+type downloadConfig struct {
+	progress func(done, total int64)
+	sha256   string
+}
+
+// OnProgress вызывает fn по мере скачивания: done - сколько байт файла
+// уже получено, включая докачанные ранее, total - полный размер
+// или -1, если сервер его не сообщил
+func OnProgress(fn func(done, total int64)) DownloadOption {
+	return func(c *downloadConfig) {
+		c.progress = fn
+	}
+}
+
+// SHA256 проверяет контрольную сумму скачанного файла.
+// sum - SHA-256 в шестнадцатеричной записи.
+func SHA256(sum string) DownloadOption {
+	return func(c *downloadConfig) {
+		c.sha256 = strings.ToLower(sum)
+	}
+}
+
+// Download скачивает тело ответа на GET-запрос в файл path.
+//
+// Данные сначала пишутся в path.part, а после успешного скачивания
+// файл атомарно переименовывается в path. Если path.part остался
+// от прерванного скачивания, Download докачивает его Range-запросом.
+// Рядом хранится ETag (или Last-Modified) ответа: если файл
+// на сервере изменился, скачивание начинается заново.
+//
+// Ответ не содержит тела, а ошибка доступна через r.Err().
+// При ошибке чтения path.part сохраняется для докачки,
+// при несовпадении контрольной суммы - удаляется.
+func (h *Handy) Download(ctx context.Context, path string, opts ...DownloadOption) *HandyResponse {
+	var config downloadConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	part := path + ".part"
+	validatorPath := part + ".etag"
+
+	// resume
+	var offset int64
+	validator, _ := os.ReadFile(validatorPath)
+	if info, err := os.Stat(part); err == nil && len(validator) > 0 {
+		offset = info.Size()
+	}
+
+	r := h.downloadRequest(ctx, offset, string(validator))
+	if r.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// докачать не получилось - скачиваем заново
+		r.Close()
+		offset = 0
+		r = h.downloadRequest(ctx, 0, "")
+	}
+	if r.Err() != nil {
+		r.Close()
+		return r
+	}
+	defer r.Close()
+
+	total := int64(-1)
+	switch r.StatusCode {
+	case http.StatusPartialContent:
+		start, size, err := parseContentRange(r.Header.Get("Content-Range"))
+		if err != nil {
+			return errorResponse(err)
+		}
+		if start != offset {
+			return errorResponse(fmt.Errorf("download: got range from %d, want %d", start, offset))
+		}
+		total = size
+	default:
+		// сервер прислал файл целиком
+		offset = 0
+		if size, err := strconv.ParseInt(r.Header.Get("Content-Length"), 10, 64); err == nil {
+			total = size
+		}
+	}
+
+	// ETag подходит для If-Range, только если он не слабый
+	validator = nil
+	if etag := r.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		validator = []byte(etag)
+	} else if modified := r.Header.Get("Last-Modified"); modified != "" {
+		validator = []byte(modified)
+	}
+	if validator != nil {
+		if err := os.WriteFile(validatorPath, validator, 0o644); err != nil {
+			return errorResponse(err)
+		}
+	} else {
+		os.Remove(validatorPath)
+	}
+
+	file, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return errorResponse(err)
+	}
+	defer file.Close()
+
+	// контрольная сумма считается по всему файлу, включая начало,
+	// скачанное раньше
+	hasher := sha256.New()
+	if offset > 0 {
+		if _, err := io.CopyN(hasher, file, offset); err != nil {
+			return errorResponse(err)
+		}
+	} else if err := file.Truncate(0); err != nil {
+		return errorResponse(err)
+	}
+
+	progress := &progressWriter{done: offset, total: total, fn: config.progress}
+
+	if _, err := io.Copy(io.MultiWriter(file, hasher, progress), r.Body); err != nil {
+		return errorResponse(err)
+	}
+	if err := file.Sync(); err != nil {
+		return errorResponse(err)
+	}
+	if err := file.Close(); err != nil {
+		return errorResponse(err)
+	}
+
+	if err := verifyChecksum(hasher, config.sha256); err != nil {
+		os.Remove(part)
+		os.Remove(validatorPath)
+		return errorResponse(err)
+	}
+
+	if err := os.Rename(part, path); err != nil {
+		return errorResponse(err)
+	}
+	os.Remove(validatorPath)
+
+	return &HandyResponse{
+		StatusCode: r.StatusCode,
+		Header:     r.Header,
+		Attempts:   r.Attempts,
+		Timings:    r.Timings,
+	}
+}
+
+// downloadRequest запрашивает файл начиная с offset.
+// Запрос выполняется на копии h, чтобы не менять ее заголовки.
+func (h *Handy) downloadRequest(ctx context.Context, offset int64, validator string) *HandyResponse {
+	req := h.Clone().Stream()
+	// смещение считается в байтах файла, а не сжатого ответа
+	req.Header("Accept-Encoding", "identity")
+	if offset > 0 {
+		req.Header("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header("If-Range", validator)
+	}
+	return req.DoContext(ctx, http.MethodGet)
+}
+
+// parseContentRange разбирает заголовок Content-Range вида
+// "bytes 100-199/200" и возвращает начало диапазона и полный размер
+// (-1, если он неизвестен)
+func parseContentRange(value string) (start, total int64, err error) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, fmt.Errorf("download: invalid Content-Range %q", value)
+	}
+
+	rng, size, ok := strings.Cut(spec, "/")
+	first, _, ok2 := strings.Cut(rng, "-")
+	if !ok || !ok2 {
+		return 0, 0, fmt.Errorf("download: invalid Content-Range %q", value)
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("download: invalid Content-Range %q", value)
+	}
+	if size == "*" {
+		return start, -1, nil
+	}
+	total, err = strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("download: invalid Content-Range %q", value)
+	}
+	return start, total, nil
+}
+
+// verifyChecksum сравнивает сумму из hasher с ожидаемой want.
+// Пустая want означает, что проверка не нужна.
+func verifyChecksum(hasher hash.Hash, want string) error {
+	if want == "" {
+		return nil
+	}
+	got := hex.EncodeToString(hasher.Sum(nil))
+	if got != want {
+		return fmt.Errorf("%w: got sha256 %s, want %s", ErrChecksumMismatch, got, want)
+	}
+	return nil
+}
+
+// progressWriter сообщает о количестве записанных байт
+type progressWriter struct {
+	done  int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.done += int64(len(p))
+	if w.fn != nil {
+		w.fn(w.done, w.total)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	var ranges atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			ranges.Add(1)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		name      string
+		part      []byte
+		etag      string
+		checksum  string
+		wantRange bool
+		wantErr   error
+	}{
+		{"full", nil, "", checksum, false, nil},
+		{"resume", content[:4000], `"v1"`, checksum, true, nil},
+		{"changed", []byte("stale content"), `"v0"`, checksum, true, nil},
+		{"no etag", []byte("stale content"), "", checksum, false, nil},
+		{"checksum", nil, "", strings.Repeat("0", 64), false, ErrChecksumMismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.bin")
+			if test.part != nil {
+				os.WriteFile(path+".part", test.part, 0o644)
+			}
+			if test.etag != "" {
+				os.WriteFile(path+".part.etag", []byte(test.etag), 0o644)
+			}
+			ranges.Store(0)
+
+			var done, total int64
+			resp := NewHandy().URL(srv.URL).Download(t.Context(), path,
+				SHA256(test.checksum),
+				OnProgress(func(d, t int64) { done, total = d, t }))
+
+			if !errors.Is(resp.Err(), test.wantErr) {
+				t.Fatalf("got error %v, want %v", resp.Err(), test.wantErr)
+			}
+			if got := ranges.Load() > 0; got != test.wantRange {
+				t.Errorf("range request: got %v, want %v", got, test.wantRange)
+			}
+			if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+				t.Errorf("part file: got %v, want removed", err)
+			}
+			if test.wantErr != nil {
+				return
+			}
+
+			data, _ := os.ReadFile(path)
+			if !bytes.Equal(data, content) {
+				t.Errorf("got %d bytes, want %d", len(data), len(content))
+			}
+			if done != int64(len(content)) || total != int64(len(content)) {
+				t.Errorf("progress: got %d/%d, want %d/%d", done, total, len(content), len(content))
+			}
+		})
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	var tests = []struct {
+		value     string
+		wantStart int64
+		wantTotal int64
+		wantErr   bool
+	}{
+		{"bytes 100-199/200", 100, 200, false},
+		{"bytes 0-9/*", 0, -1, false},
+		{"bytes */200", 0, 0, true},
+		{"items 0-9/10", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			start, total, err := parseContentRange(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if start != test.wantStart || total != test.wantTotal {
+				t.Errorf("got %d/%d, want %d/%d", start, total, test.wantStart, test.wantTotal)
+			}
+		})
+	}
+}
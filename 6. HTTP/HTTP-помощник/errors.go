@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// StatusErr описывает ситуацию, когда на запрос
+// пришел ответ с HTTP-статусом, отличным от 2xx.
+type StatusErr struct {
+	Code   int
+	Status string
+	// Header - заголовки ответа
+	Header http.Header
+	// Body - тело ответа, не длиннее maxErrorBody байт
+	Body []byte
+	// Problem - описание ошибки, если ответ пришел
+	// в формате application/problem+json (RFC 7807)
+	Problem *Problem
+}
+
+func (e StatusErr) Error() string {
+	if e.Problem != nil {
+		return "invalid response status: " + e.Status + ": " + e.Problem.Error()
+	}
+	return "invalid response status: " + e.Status
+}
+
+// Unwrap возвращает Problem, чтобы его можно было
+// получить через errors.As
+func (e StatusErr) Unwrap() error {
+	if e.Problem == nil {
+		return nil
+	}
+	return e.Problem
+}
+
+// Problem описывает ошибку в формате application/problem+json (RFC 7807)
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+}
+
+func (p *Problem) Error() string {
+	switch {
+	case p.Title != "" && p.Detail != "":
+		return p.Title + ": " + p.Detail
+	case p.Detail != "":
+		return p.Detail
+	case p.Title != "":
+		return p.Title
+	}
+	return p.Type
+}
+
+// maxErrorBody ограничивает размер тела ответа, сохраняемого в StatusErr
+const maxErrorBody = 64 << 10
+
+// newStatusErr создает ошибку для ответа с HTTP-статусом, отличным от 2xx.
+// Если ответ в формате application/problem+json, разбирает его в Problem.
+func newStatusErr(resp *http.Response, body []byte) StatusErr {
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+
+	err := StatusErr{
+		Code:   resp.StatusCode,
+		Status: resp.Status,
+		Header: resp.Header,
+		Body:   body,
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/problem+json" {
+		var problem Problem
+		if json.Unmarshal(body, &problem) == nil {
+			err.Problem = &problem
+		}
+	}
+
+	return err
+}
+
+// successful проверяет, что HTTP-статус ответа 2xx
+func successful(resp *http.Response) bool {
+	return resp.StatusCode >= 200 && resp.StatusCode <= 299
+}
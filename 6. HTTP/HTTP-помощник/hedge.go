@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// hedgeSamples - сколько последних задержек хранит Hedger
+// для расчета перцентиля
+const hedgeSamples = 1000
+
+// HedgeSettings настраивает отправку дублирующих запросов
+type HedgeSettings struct {
+	// Delay - через сколько отправить дубль, если ответа еще нет
+	Delay time.Duration
+	// Percentile, если задан (например, 0.95), заменяет Delay
+	// соответствующим перцентилем задержек уже полученных ответов.
+	// Пока ответов меньше MinSamples, используется Delay.
+	Percentile float64
+	// MinSamples - сколько ответов нужно для расчета перцентиля,
+	// по умолчанию 20
+	MinSamples int
+	// MaxHedges - сколько дублей можно отправить на один запрос,
+	// по умолчанию 1
+	MaxHedges int
+}
+
+// HedgeStats - статистика дублирующих запросов
+type HedgeStats struct {
+	// Requests - запросы, прошедшие через Hedger
+	Requests int
+	// Hedged - запросы, для которых отправлен хотя бы один дубль
+	Hedged int
+	// Hedges - отправленные дубли
+	Hedges int
+	// Wins - запросы, на которые первым ответил дубль
+	Wins int
+}
+
+// Hedger отправляет дубль запроса, если ответ задерживается,
+// и возвращает тот ответ, что пришел первым. Остальные запросы
+// отменяются. Так сокращается время самых медленных ответов
+// ценой дополнительной нагрузки на сервер.
+//
+// Дублируются только идемпотентные запросы. Hedger можно использовать
+// в нескольких Handy одновременно: задержки и статистика у них общие.
+type Hedger struct {
+	settings HedgeSettings
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+	stats     HedgeStats
+}
+
+// NewHedger создает Hedger с указанными настройками
+func NewHedger(settings HedgeSettings) *Hedger {
+	if settings.MinSamples <= 0 {
+		settings.MinSamples = 20
+	}
+	if settings.MaxHedges <= 0 {
+		settings.MaxHedges = 1
+	}
+	return &Hedger{settings: settings}
+}
+
+// Hedge включает дублирование медленных запросов
+func (h *Handy) Hedge(hedger *Hedger) *Handy {
+	h.hedger = hedger
+	return h
+}
+
+// Stats возвращает статистику дублирующих запросов
+func (g *Hedger) Stats() HedgeStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// delay возвращает, через сколько отправлять дубль
+func (g *Hedger) delay() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.settings.Percentile <= 0 || len(g.latencies) < g.settings.MinSamples {
+		return g.settings.Delay
+	}
+
+	sorted := slices.Clone(g.latencies)
+	slices.Sort(sorted)
+	i := int(g.settings.Percentile * float64(len(sorted)))
+	return sorted[min(i, len(sorted)-1)]
+}
+
+// observe учитывает результат запроса
+func (g *Hedger) observe(latency time.Duration, hedges int, hedgeWon bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.latencies) < hedgeSamples {
+		g.latencies = append(g.latencies, latency)
+	} else {
+		g.latencies[g.next] = latency
+		g.next = (g.next + 1) % hedgeSamples
+	}
+
+	g.stats.Requests++
+	g.stats.Hedges += hedges
+	if hedges > 0 {
+		g.stats.Hedged++
+	}
+	if hedgeWon {
+		g.stats.Wins++
+	}
+}
+
+// hedgeResult - результат одного из параллельных запросов
+type hedgeResult struct {
+	// i - номер запроса, 0 - исходный, остальные - дубли
+	i    int
+	resp *http.Response
+	err  error
+}
+
+// hedge отправляет дубли медленных запросов через g
+func hedge(g *Hedger) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if !isIdempotent(req.Method) || !replayable(req) {
+				return next(req)
+			}
+
+			start := time.Now()
+			results := make(chan hedgeResult, g.settings.MaxHedges+1)
+			var cancels []context.CancelFunc
+			pending := 0
+
+			launch := func() error {
+				ctx, cancel := context.WithCancel(req.Context())
+				r := req.Clone(ctx)
+				if len(cancels) > 0 && req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						cancel()
+						return err
+					}
+					r.Body = body
+				}
+
+				i := len(cancels)
+				cancels = append(cancels, cancel)
+				pending++
+				go func() {
+					resp, err := next(r)
+					results <- hedgeResult{i, resp, err}
+				}()
+				return nil
+			}
+
+			// отменяет запросы, кроме winner, и закрывает их ответы
+			abandon := func(winner int) {
+				for i, cancel := range cancels {
+					if i != winner {
+						cancel()
+					}
+				}
+				go func(pending int) {
+					for range pending {
+						if res := <-results; res.resp != nil {
+							res.resp.Body.Close()
+						}
+					}
+				}(pending)
+			}
+
+			if err := launch(); err != nil {
+				return nil, err
+			}
+
+			var timer <-chan time.Time
+			if delay := g.delay(); delay > 0 {
+				t := time.NewTimer(delay)
+				defer t.Stop()
+				timer = t.C
+			}
+
+			for {
+				select {
+				case <-timer:
+					if err := launch(); err != nil {
+						timer = nil
+						continue
+					}
+					if len(cancels) <= g.settings.MaxHedges {
+						timer = time.After(g.delay())
+					} else {
+						timer = nil
+					}
+
+				case res := <-results:
+					pending--
+					// при ошибке ждем ответа на остальные запросы, если они есть
+					if (res.err != nil || res.resp.StatusCode >= 500) && pending > 0 {
+						if res.resp != nil {
+							discard(res.resp)
+						}
+						cancels[res.i]()
+						continue
+					}
+
+					abandon(res.i)
+					g.observe(time.Since(start), len(cancels)-1, res.i > 0)
+
+					if res.err != nil {
+						cancels[res.i]()
+						return nil, res.err
+					}
+					res.resp.Body = &streamBody{res.resp.Body, cancels[res.i]}
+					return res.resp, nil
+				}
+			}
+		}
+	}
+}
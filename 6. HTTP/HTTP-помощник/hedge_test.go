@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedge(t *testing.T) {
+	var requests atomic.Int32
+	cancelled := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// первый запрос зависает, пока его не отменят
+		if requests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+				cancelled <- struct{}{}
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		io.WriteString(w, "fast")
+	}))
+	defer srv.Close()
+
+	hedger := NewHedger(HedgeSettings{Delay: 20 * time.Millisecond})
+
+	start := time.Now()
+	resp := NewHandy().URL(srv.URL).Hedge(hedger).Get()
+	if resp.Err() != nil {
+		t.Fatal(resp.Err())
+	}
+	if got := resp.String(); got != "fast" {
+		t.Errorf("got %q, want %q", got, "fast")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("got %v, want hedge to answer first", elapsed)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("slow request was not cancelled")
+	}
+
+	// POST не дублируется
+	requests.Store(1)
+	NewHandy().URL(srv.URL).Hedge(hedger).Post()
+	if got := requests.Load(); got != 2 {
+		t.Errorf("POST: got %d requests, want 2", got)
+	}
+
+	want := HedgeStats{Requests: 1, Hedged: 1, Hedges: 1, Wins: 1}
+	if got := hedger.Stats(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestHedgePercentile(t *testing.T) {
+	var tests = []struct {
+		name    string
+		samples []time.Duration
+		want    time.Duration
+	}{
+		{"too few samples", []time.Duration{time.Millisecond}, time.Second},
+		{"p90", func() []time.Duration {
+			var samples []time.Duration
+			for i := 1; i <= 100; i++ {
+				samples = append(samples, time.Duration(i)*time.Millisecond)
+			}
+			return samples
+		}(), 91 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hedger := NewHedger(HedgeSettings{Delay: time.Second, Percentile: 0.9})
+			for _, latency := range test.samples {
+				hedger.observe(latency, 0, false)
+			}
+			if got := hedger.delay(); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,19 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,26 +23,48 @@ import (
 // Handy предоставляет удобный интерфейс
 // для выполнения HTTP-запросов
 type Handy struct {
-	url     string
-	client  *http.Client
-	headers map[string]string
-	params  *url.Values
-	body    []byte
-	error   error
+	url         string
+	baseURL     string
+	pathParams  map[string]string
+	method      string
+	ctx         context.Context
+	timeout     time.Duration
+	client      *http.Client
+	headers     map[string]string
+	params      *url.Values
+	body        []byte
+	multipart   *multipartBody
+	compress    bool
+	retry       *RetryPolicy
+	auth        Authenticator
+	cache       CacheStore
+	breaker     *CircuitBreaker
+	hedger      *Hedger
+	jar         http.CookieJar
+	middlewares []Middleware
+	stream      bool
+	trace       bool
+	maxPages    int
+	maxBodySize int64
+	error       error
 }
 
 // NewHandy создает новый экземпляр Handy
 func NewHandy() *Handy {
 	return &Handy{
-		client:  &http.Client{Timeout: 3 * time.Millisecond},
-		headers: map[string]string{},
-		params:  &url.Values{},
-		error:   nil,
-		body:    nil,
+		ctx:        context.Background(),
+		client:     &http.Client{},
+		headers:    map[string]string{},
+		pathParams: map[string]string{},
+		params:     &url.Values{},
+		error:      nil,
+		body:       nil,
 	}
 }
 
-// URL устанавливает URL, на который пойдет запрос
+// URL устанавливает URL, на который пойдет запрос.
+// URL может быть шаблоном с подстановками вида {name}
+// (см. PathParam) и относительным (см. BaseURL).
 func (h *Handy) URL(uri string) *Handy {
 	h.url = uri
 	return h
@@ -47,9 +77,25 @@ func (h *Handy) Client(client *http.Client) *Handy {
 	return h
 }
 
-// Header устанавливает значение заголовка
+// Context устанавливает контекст, с которым будут выполняться запросы.
+// При отмене контекста запрос прерывается, а ответ содержит
+// ошибку context.Canceled или context.DeadlineExceeded.
+func (h *Handy) Context(ctx context.Context) *Handy {
+	h.ctx = ctx
+	return h
+}
+
+// Timeout ограничивает время выполнения каждого запроса,
+// включая повторы и чтение ответа
+func (h *Handy) Timeout(d time.Duration) *Handy {
+	h.timeout = d
+	return h
+}
+
+// Header устанавливает значение заголовка. Регистр имени не важен:
+// как и при вызове JSON или Form, новое значение заменяет прежнее.
 func (h *Handy) Header(key, value string) *Handy {
-	h.headers[key] = value
+	h.headers[http.CanonicalHeaderKey(key)] = value
 	return h
 }
 
@@ -63,117 +109,284 @@ func (h *Handy) Param(key, value string) *Handy {
 // как application/x-www-form-urlencoded и отправлены в теле запроса
 // с соответствующим content-type
 func (h *Handy) Form(form map[string]string) *Handy {
-	formBody := &url.Values{}
-	for k, v := range form {
-		formBody.Add(k, v)
-	}
-
-	h.headers["Content-Type"] = "application/x-www-form-urlencoded"
-	h.body = []byte(formBody.Encode())
-
-	return h
+	return h.Body(form, formMediaType)
 }
 
 // JSON устанавливает данные, которые будут закодированы
 // как application/json и отправлены в теле запроса
 // с соответствующим content-type
 func (h *Handy) JSON(v any) *Handy {
-	body, err := json.Marshal(v)
+	return h.Body(v, jsonMediaType)
+}
 
-	if err != nil {
-		h.error = err
-		return h
-	}
+// Get выполняет GET-запрос с настроенными ранее параметрами
+func (h *Handy) Get() *HandyResponse {
+	return h.Do(http.MethodGet)
+}
 
-	h.headers["Content-Type"] = "application/json"
-	h.body = body
+// GetContext выполняет GET-запрос с указанным контекстом
+func (h *Handy) GetContext(ctx context.Context) *HandyResponse {
+	return h.DoContext(ctx, http.MethodGet)
+}
+
+// Post выполняет POST-запрос с настроенными ранее параметрами
+func (h *Handy) Post() *HandyResponse {
+	return h.Do(http.MethodPost)
+}
+
+// PostContext выполняет POST-запрос с указанным контекстом
+func (h *Handy) PostContext(ctx context.Context) *HandyResponse {
+	return h.DoContext(ctx, http.MethodPost)
+}
+
+// Put выполняет PUT-запрос с настроенными ранее параметрами
+func (h *Handy) Put() *HandyResponse {
+	return h.Do(http.MethodPut)
+}
+
+// Patch выполняет PATCH-запрос с настроенными ранее параметрами
+func (h *Handy) Patch() *HandyResponse {
+	return h.Do(http.MethodPatch)
+}
+
+// Delete выполняет DELETE-запрос с настроенными ранее параметрами
+func (h *Handy) Delete() *HandyResponse {
+	return h.Do(http.MethodDelete)
+}
+
+// Head выполняет HEAD-запрос с настроенными ранее параметрами.
+// Тело ответа на HEAD-запрос всегда пустое.
+func (h *Handy) Head() *HandyResponse {
+	return h.Do(http.MethodHead)
+}
+
+// Options выполняет OPTIONS-запрос с настроенными ранее параметрами
+func (h *Handy) Options() *HandyResponse {
+	return h.Do(http.MethodOptions)
+}
 
+// Method устанавливает HTTP-метод, которым запрос выполняют
+// Send и Batch. По умолчанию - GET.
+func (h *Handy) Method(method string) *Handy {
+	h.method = method
 	return h
 }
 
-// Get выполняет GET-запрос с настроенными ранее параметрами
-func (h *Handy) Get() *HandyResponse {
-	if h.error != nil {
-		return &HandyResponse{0, nil, h.error}
+// Send выполняет запрос методом, установленным через Method
+func (h *Handy) Send() *HandyResponse {
+	return h.Do(h.requestMethod())
+}
+
+// requestMethod возвращает метод, установленный через Method,
+// или GET, если метод не задан
+func (h *Handy) requestMethod() string {
+	if h.method == "" {
+		return http.MethodGet
 	}
+	return h.method
+}
+
+// Do выполняет запрос указанным HTTP-методом
+// с настроенными ранее параметрами.
+// Через Do проходят все остальные методы запросов.
+func (h *Handy) Do(method string) *HandyResponse {
+	return h.DoContext(h.ctx, method)
+}
 
-	request, requestError := http.NewRequest(http.MethodGet, h.url, bytes.NewReader(h.body))
-	if requestError != nil {
-		return &HandyResponse{0, nil, requestError}
+// DoContext выполняет запрос указанным HTTP-методом
+// с указанным контекстом вместо настроенного через Context
+func (h *Handy) DoContext(ctx context.Context, method string) *HandyResponse {
+	if h.error != nil {
+		return errorResponse(h.error)
 	}
 
-	// get parameters
-	request.URL.RawQuery = h.params.Encode()
+	cancel := context.CancelFunc(func() {})
+	if h.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+	}
 
-	// headers
-	for k, v := range h.headers {
-		request.Header.Add(k, v)
+	var trace *tracer
+	if h.trace {
+		trace = newTracer()
+		ctx = trace.withContext(ctx)
 	}
 
 	// make request
-	resp, responseErr := h.client.Do(request)
-	if responseErr != nil {
-		return &HandyResponse{0, nil, responseErr}
+	resp, attempts, err := h.send(ctx, method)
+	if err != nil {
+		cancel()
+		r := errorResponse(err)
+		r.Attempts = attempts
+		r.Timings = trace.result()
+		return r
+	}
+
+	body, err := limitBody(resp, h.maxBodySize)
+	if err != nil {
+		cancel()
+		r := errorResponse(err)
+		r.Attempts = attempts
+		r.Timings = trace.result()
+		return r
 	}
-	defer resp.Body.Close()
+
+	// при потоковом чтении тело закрывает вызывающая сторона
+	if h.stream {
+		r := &HandyResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Attempts:   attempts,
+			Timings:    trace.result(),
+		}
+		if !successful(resp) {
+			// начало тела нужно для ошибки, но оно остается доступно для чтения
+			prefix, _ := io.ReadAll(io.LimitReader(body, maxErrorBody))
+			r.error = newStatusErr(resp, prefix)
+			body = readCloser{io.MultiReader(bytes.NewReader(prefix), body), body}
+		}
+		r.Body = &streamBody{body, cancel}
+		return r
+	}
+	defer cancel()
+	defer body.Close()
 
 	// read response
-	body, readResponseError := io.ReadAll(resp.Body)
-	if readResponseError != nil {
-		return &HandyResponse{0, nil, readResponseError}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		r := errorResponse(err)
+		r.Attempts = attempts
+		r.Timings = trace.result()
+		return r
 	}
 
-	return &HandyResponse{
+	r := &HandyResponse{
 		StatusCode:   resp.StatusCode,
-		ResponseBody: body,
+		Header:       resp.Header,
+		ResponseBody: data,
+		Attempts:     attempts,
+		Timings:      trace.result(),
 		error:        nil,
 	}
+	if !successful(resp) {
+		r.error = newStatusErr(resp, data)
+	}
+	return r
 }
 
-// Post выполняет POST-запрос с настроенными ранее параметрами
-func (h *Handy) Post() *HandyResponse {
-	if h.error != nil {
-		return &HandyResponse{0, nil, h.error}
+// send отправляет запрос, повторяя его по политике повторов,
+// и возвращает последний полученный ответ
+func (h *Handy) send(ctx context.Context, method string) (*http.Response, int, error) {
+	roundTrip := h.roundTrip()
+
+	// тело сжимается один раз для всех попыток
+	body, err := h.requestBody()
+	if err != nil {
+		return nil, 1, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		request, err := h.newRequest(ctx, method, body)
+		if err != nil {
+			return nil, attempt, err
+		}
+
+		resp, err := roundTrip(request)
+		if !replayable(request) || !h.retry.retryable(method, attempt, resp, err) {
+			return resp, attempt, err
+		}
+
+		delay := h.retry.backoff(attempt, resp)
+		if resp != nil {
+			discard(resp)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, attempt, err
+		}
 	}
+}
 
-	request, requestError := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(h.body))
-	if requestError != nil {
-		return &HandyResponse{0, nil, requestError}
+// requestBody возвращает тело запроса, сжатое, если включен Compress.
+// Тело multipart-формы собирается при каждой попытке в newRequest.
+func (h *Handy) requestBody() ([]byte, error) {
+	if h.body == nil || h.multipart != nil || !h.compress {
+		return h.body, nil
 	}
+	return gzipBytes(h.body)
+}
 
-	// get parameters
-	request.URL.RawQuery = h.params.Encode()
+// newRequest собирает HTTP-запрос из настроенных ранее
+// URL, параметров, заголовков и тела data из requestBody
+func (h *Handy) newRequest(ctx context.Context, method string, data []byte) (*http.Request, error) {
+	uri, err := h.requestURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	switch {
+	case h.multipart != nil:
+		body = h.multipart.reader()
+	case data != nil:
+		body = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, uri, body)
+	if err != nil {
+		if closer, ok := body.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
 
 	// headers
 	for k, v := range h.headers {
-		request.Header.Add(k, v)
+		request.Header.Set(k, v)
+	}
+	if h.compress && h.multipart == nil && h.body != nil {
+		request.Header.Set("Content-Encoding", "gzip")
 	}
 
-	// make request
-	resp, responseErr := h.client.Do(request)
-	if responseErr != nil {
-		return &HandyResponse{0, nil, responseErr}
+	return request, nil
+}
+
+// requestURL возвращает URL запроса вместе с URL-параметрами
+func (h *Handy) requestURL() (string, error) {
+	uri, err := h.resolveURL()
+	if err != nil {
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	// read response
-	body, readResponseError := io.ReadAll(resp.Body)
-	if readResponseError != nil {
-		return &HandyResponse{0, nil, readResponseError}
+	if len(*h.params) == 0 {
+		return uri, nil
 	}
 
-	return &HandyResponse{
-		StatusCode:   resp.StatusCode,
-		ResponseBody: body,
-		error:        nil,
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	// get parameters
+	query := u.Query()
+	for k, values := range *h.params {
+		query[k] = values
 	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
 }
 
 // HandyResponse представляет ответ на HTTP-запрос
 type HandyResponse struct {
 	StatusCode   int
+	Header       http.Header
 	ResponseBody []byte
-	error        error
+	// Body - непрочитанное тело ответа при потоковом чтении (см. Handy.Stream)
+	Body io.ReadCloser
+	// Attempts - сколько попыток понадобилось для получения ответа
+	Attempts int
+	// Timings - время этапов запроса, если включен Trace
+	Timings *Timings
+	error   error
 }
 
 // OK возвращает true, если во время выполнения запроса
@@ -184,11 +397,13 @@ func (r *HandyResponse) OK() bool {
 
 // Bytes возвращает тело ответа как срез байт
 func (r *HandyResponse) Bytes() []byte {
+	r.load()
 	return r.ResponseBody
 }
 
 // String возвращает тело ответа как строку
 func (r *HandyResponse) String() string {
+	r.load()
 	return string(r.ResponseBody)
 }
 
@@ -199,19 +414,22 @@ func (r *HandyResponse) JSON(v any) {
 	// если при декодировании произошла ошибка,
 	// она должна быть доступна через r.Err()
 
-	err := json.Unmarshal(r.ResponseBody, v)
-
-	if err != nil {
-		r.error = err
-	}
+	r.decode(jsonMediaType, v)
 }
 
 // Err возвращает ошибку, которая возникла при выполнении запроса
-// или обработке ответа
+// или обработке ответа. Для ответов с HTTP-статусом, отличным от 2xx,
+// возвращает StatusErr.
 func (r *HandyResponse) Err() error {
 	return r.error
 }
 
+// errorResponse создает ответ для запроса,
+// который не удалось выполнить
+func errorResponse(err error) *HandyResponse {
+	return &HandyResponse{error: err}
+}
+
 // конец решения
 
 func main() {
@@ -237,6 +455,173 @@ func main() {
 
 		// POST JSON-документа
 		NewHandy().URL("https://httpbingo.org/post").JSON(params).Post()
+
+		// PUT и DELETE
+		NewHandy().URL("https://httpbingo.org/put").JSON(params).Put()
+		NewHandy().URL("https://httpbingo.org/delete").Param("id", "42").Delete()
+
+		// произвольный метод
+		NewHandy().URL("https://httpbingo.org/anything").Do("PURGE")
+
+		// загрузка файла
+		NewHandy().
+			URL("https://httpbingo.org/post").
+			Multipart(
+				map[string]string{"title": "report"},
+				FilePart{"file", "report.txt", "text/plain", strings.NewReader("hello")},
+			).
+			Post()
+
+		// аутентификация
+		NewHandy().URL("https://httpbingo.org/bearer").Auth(BearerToken("1234567890")).Get()
+
+		// кэширование ответов
+		cache := NewMemoryCache(100)
+		for range 3 {
+			NewHandy().URL("https://httpbingo.org/cache/60").Cache(cache).Get()
+		}
+
+		// предохранитель
+		breaker := NewCircuitBreaker(BreakerSettings{
+			ConsecutiveFailures: 5,
+			Cooldown:            10 * time.Second,
+			OnStateChange: func(host string, from, to CircuitState) {
+				fmt.Println(host, from, "->", to)
+			},
+		})
+		NewHandy().URL("https://httpbingo.org/status/500").CircuitBreaker(breaker).Get()
+
+		// сессия в файле
+		jar, err := NewCookieJar(filepath.Join(os.TempDir(), "session.json"))
+		if err == nil {
+			NewHandy().URL("https://httpbingo.org/cookies/set").Param("session", "42").CookieJar(jar).Get()
+			jar.Save()
+		}
+
+		// сжатие тела запроса
+		NewHandy().URL("https://httpbingo.org/post").JSON(params).Compress().Post()
+
+		// постраничный обход
+		pages := NewHandy().URL("https://api.github.com/repos/golang/go/issues").Param("per_page", "100").MaxPages(5)
+		for resp, err := range pages.Pages(LinkPages()) {
+			if err != nil {
+				break
+			}
+			fmt.Println(len(resp.Bytes()))
+		}
+
+		// пакет запросов
+		var requests []*Handy
+		for id := range 10 {
+			requests = append(requests, NewHandy().URL("https://httpbingo.org/get").Param("id", strconv.Itoa(id)))
+		}
+		responses, err := Batch(requests, 4, PerHostLimit(2))
+		fmt.Println(len(responses), err)
+
+		// шаблон URL
+		NewHandy().
+			BaseURL("https://httpbingo.org").
+			URL("/anything/users/{id}/orders/{orderID}").
+			PathParam("id", "42").
+			PathParam("orderID", "a/b").
+			Get()
+
+		// XML в запросе и ответе
+		type department struct {
+			XMLName xml.Name `xml:"department"`
+			Code    string   `xml:"code"`
+		}
+		var dep department
+		NewHandy().URL("https://httpbingo.org/xml").Body(department{Code: "hr"}, "application/xml").Post().Decode(&dep)
+
+		// журнал запросов
+		NewHandy().
+			URL("https://httpbingo.org/get").
+			Use(LogRequests(slog.Default()), RequestID(""), UserAgent("handy/1.0")).
+			Get()
+
+		// потоковое чтение NDJSON
+		resp := NewHandy().URL("https://httpbingo.org/stream/3").Stream().Get()
+		DecodeEach(resp, func(v map[string]any) error {
+			fmt.Println(v["id"])
+			return nil
+		})
+
+		// повтор неудачных запросов
+		NewHandy().URL("https://httpbingo.org/status/503").Retry(DefaultRetryPolicy()).Get()
+
+		// общий клиент с настройками по умолчанию
+		api := NewClient(NewHandy().BaseURL("https://httpbingo.org").Header("Accept", "application/json").Timeout(5 * time.Second))
+		var wg sync.WaitGroup
+		for _, id := range []string{"1", "2"} {
+			wg.Go(func() {
+				api.New().URL("/get").Param("id", id).Get()
+			})
+		}
+		wg.Wait()
+
+		// время этапов запроса
+		timings := NewHandy().URL("https://httpbingo.org/get").Trace().Get().Timings
+		fmt.Println(timings.DNS, timings.Connect, timings.TLS, timings.FirstByte, timings.Total, timings.ConnReused)
+
+		// скачивание файла с докачкой
+		NewHandy().URL("https://httpbingo.org/bytes/1024").Download(context.Background(), "bytes.bin",
+			OnProgress(func(done, total int64) { fmt.Printf("%d/%d\n", done, total) }))
+
+		// собственный CA, клиентский сертификат и закрепление ключа
+		// клиент создается один раз, чтобы запросы использовали общий пул соединений
+		caPEM, _ := os.ReadFile("ca.pem")
+		certPEM, _ := os.ReadFile("client.pem")
+		keyPEM, _ := os.ReadFile("client-key.pem")
+		tlsClient, err := NewTLSClient(TLSConfig{
+			RootCAs:    caPEM,
+			ClientCert: certPEM,
+			ClientKey:  keyPEM,
+			Pins:       []string{"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+		})
+		if err == nil {
+			internal := NewClient(NewHandy().Client(tlsClient).BaseURL("https://internal.example.com"))
+			internal.New().URL("/status").Get()
+			internal.New().URL("/health").Get()
+		}
+
+		// дублирование медленных запросов
+		hedger := NewHedger(HedgeSettings{Delay: 100 * time.Millisecond, Percentile: 0.95})
+		for range 10 {
+			NewHandy().URL("https://httpbingo.org/get").Hedge(hedger).Get()
+		}
+		fmt.Printf("%+v\n", hedger.Stats())
+
+		// поток событий Server-Sent Events
+		// после закрытия потока Events переподключается, поэтому обход прерываем сами
+		received := 0
+		for event, err := range NewHandy().URL("https://httpbingo.org/sse?count=3").Events(context.Background()) {
+			if err != nil {
+				break
+			}
+			fmt.Println(event.ID, event.Event, event.Data)
+			if received++; received == 3 {
+				break
+			}
+		}
+
+		// запрос в виде команды curl и обратно
+		fmt.Println(NewHandy().URL("https://httpbingo.org/post").JSON(map[string]int{"id": 42}).Curl())
+		// curl https://httpbingo.org/post -H 'Content-Type: application/json' --data-binary '{"id":42}'
+
+		if h, err := ParseCurl(`curl -u bob:secret -d 'brand=lg' https://httpbingo.org/post`); err == nil {
+			h.Send()
+		}
+	}
+
+	{
+		// запрос с ограничением по времени
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		resp := NewHandy().URL("https://httpbingo.org/delay/3").GetContext(ctx)
+		fmt.Println(errors.Is(resp.Err(), context.DeadlineExceeded))
+		// true
 	}
 
 	{
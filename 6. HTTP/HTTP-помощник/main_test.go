@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startServer запускает тестовый сервер с обработчиком handler
+// и считает полученные им запросы. Сервер закрывается после теста.
+func startServer(t *testing.T, handler http.Handler) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestHeaderCase(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Join(r.Header.Values("Content-Type"), ", ")))
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		name string
+		h    func() *Handy
+		want string
+	}{
+		{"header then json", func() *Handy {
+			return NewHandy().Header("content-type", "application/vnd+json").JSON(1)
+		}, "application/json"},
+		{"json then header", func() *Handy {
+			return NewHandy().JSON(1).Header("content-type", "application/vnd+json")
+		}, "application/vnd+json"},
+		{"same header", func() *Handy {
+			return NewHandy().Header("CONTENT-TYPE", "text/plain").Header("content-type", "text/csv")
+		}, "text/csv"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// порядок обхода map случаен, поэтому запрос повторяется
+			for range 20 {
+				if got := test.h().URL(srv.URL).Post().String(); got != test.want {
+					t.Fatalf("got %q, want %q", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// ответ на HEAD без тела, поэтому запрос описывается в заголовке
+		w.Header().Set("X-Echo", r.Method+" "+r.URL.RawQuery+" "+string(body))
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		method string
+		send   func(h *Handy) *HandyResponse
+	}{
+		{http.MethodGet, (*Handy).Get},
+		{http.MethodPost, (*Handy).Post},
+		{http.MethodPut, (*Handy).Put},
+		{http.MethodPatch, (*Handy).Patch},
+		{http.MethodDelete, (*Handy).Delete},
+		{http.MethodHead, (*Handy).Head},
+		{http.MethodOptions, (*Handy).Options},
+		{"PURGE", func(h *Handy) *HandyResponse { return h.Do("PURGE") }},
+		{http.MethodPut, func(h *Handy) *HandyResponse { return h.Method(http.MethodPut).Send() }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			resp := test.send(NewHandy().URL(srv.URL).Param("id", "42").JSON(map[string]int{"n": 1}))
+			if resp.Err() != nil {
+				t.Fatal(resp.Err())
+			}
+			want := test.method + ` id=42 {"n":1}`
+			if got := resp.Header.Get("X-Echo"); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// /busy просит повторить запрос через 10 с, остальные зависают
+		if r.URL.Path == "/busy" {
+			w.Header().Set("Retry-After", "10")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	// cancelled возвращает контекст, который отменяется через 50 мс
+	cancelled := func(t *testing.T) context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		time.AfterFunc(50*time.Millisecond, cancel)
+		return ctx
+	}
+	expired := func(t *testing.T) context.Context {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		t.Cleanup(cancel)
+		return ctx
+	}
+	retry := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryStatuses: []int{http.StatusServiceUnavailable}}
+
+	var tests = []struct {
+		name string
+		send func(t *testing.T, h *Handy) *HandyResponse
+		want error
+	}{
+		{"Context", func(t *testing.T, h *Handy) *HandyResponse {
+			return h.Context(cancelled(t)).Get()
+		}, context.Canceled},
+		{"GetContext", func(t *testing.T, h *Handy) *HandyResponse {
+			return h.GetContext(cancelled(t))
+		}, context.Canceled},
+		{"PostContext", func(t *testing.T, h *Handy) *HandyResponse {
+			return h.PostContext(expired(t))
+		}, context.DeadlineExceeded},
+		{"Timeout", func(t *testing.T, h *Handy) *HandyResponse {
+			return h.Timeout(50 * time.Millisecond).Get()
+		}, context.DeadlineExceeded},
+		{"cancel during retry", func(t *testing.T, h *Handy) *HandyResponse {
+			return h.URL(srv.URL + "/busy").Retry(retry).GetContext(cancelled(t))
+		}, context.Canceled},
+		{"timeout during retry", func(t *testing.T, h *Handy) *HandyResponse {
+			return h.URL(srv.URL + "/busy").Retry(retry).Timeout(50 * time.Millisecond).Get()
+		}, context.DeadlineExceeded},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			resp := test.send(t, NewHandy().URL(srv.URL))
+			if !errors.Is(resp.Err(), test.want) {
+				t.Errorf("got %v, want %v", resp.Err(), test.want)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("got %v, want the request to stop at once", elapsed)
+			}
+		})
+	}
+}
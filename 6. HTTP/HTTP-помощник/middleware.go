@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RoundTripFunc отправляет HTTP-запрос и возвращает ответ
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware оборачивает отправку запроса: может изменить запрос
+// перед вызовом next, а ответ - после
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Use добавляет промежуточные обработчики, через которые проходит
+// каждая попытка запроса. Обработчик, добавленный первым,
+// вызывается первым.
+//
+// Каждая попытка получает собственный экземпляр http.Request,
+// поэтому обработчики могут менять его заголовки и URL.
+func (h *Handy) Use(middlewares ...Middleware) *Handy {
+	h.middlewares = append(h.middlewares, middlewares...)
+	return h
+}
+
+// roundTrip собирает цепочку промежуточных обработчиков
+// вокруг HTTP-клиента
+func (h *Handy) roundTrip() RoundTripFunc {
+	next := RoundTripFunc(h.client.Do)
+	if h.jar != nil {
+		client := *h.client
+		client.Jar = h.jar
+		next = client.Do
+	}
+	next = decompress(next)
+	if h.hedger != nil {
+		next = hedge(h.hedger)(next)
+	}
+	if h.breaker != nil {
+		next = breakCircuit(h.breaker)(next)
+	}
+	if h.cache != nil {
+		next = cacheResponses(h.cache, h.jar)(next)
+	}
+	// кэш видит заголовок Authorization и не кэширует такие запросы
+	if h.auth != nil {
+		next = authenticate(h.auth)(next)
+	}
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		next = h.middlewares[i](next)
+	}
+	return next
+}
+
+// closeBody закрывает тело запроса, который не будет отправлен.
+// Как и http.RoundTripper, обработчик должен закрыть тело даже при ошибке,
+// иначе, например, горутина multipart-тела будет ждать чтения вечно.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// LogRequests пишет в журнал метод, URL, статус
+// и длительность каждого запроса
+func LogRequests(logger *slog.Logger) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			elapsed := time.Since(start)
+
+			if err != nil {
+				logger.LogAttrs(req.Context(), slog.LevelError, "http request failed",
+					slog.String("method", req.Method),
+					slog.String("url", req.URL.String()),
+					slog.Duration("duration", elapsed),
+					slog.Any("error", err),
+				)
+				return resp, err
+			}
+
+			logger.LogAttrs(req.Context(), slog.LevelInfo, "http request",
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.String()),
+				slog.Int("status", resp.StatusCode),
+				slog.Duration("duration", elapsed),
+			)
+			return resp, err
+		}
+	}
+}
+
+// RequestID добавляет к запросу заголовок со случайным идентификатором,
+// если такого заголовка еще нет. По умолчанию - X-Request-ID.
+func RequestID(header string) Middleware {
+	if header == "" {
+		header = "X-Request-ID"
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				req.Header.Set(header, newRequestID())
+			}
+			return next(req)
+		}
+	}
+}
+
+// UserAgent устанавливает заголовок User-Agent,
+// если он не задан явно через Header
+func UserAgent(userAgent string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("User-Agent") == "" {
+				req.Header.Set("User-Agent", userAgent)
+			}
+			return next(req)
+		}
+	}
+}
+
+// newRequestID генерирует случайный идентификатор запроса
+func newRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
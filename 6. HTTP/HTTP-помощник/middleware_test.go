@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestUse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" before")
+				resp, err := next(req)
+				calls = append(calls, name+" after")
+				return resp, err
+			}
+		}
+	}
+
+	NewHandy().URL(srv.URL).Use(trace("a"), trace("b")).Use(trace("c")).Get()
+
+	want := "a before, b before, c before, c after, b after, a after"
+	if got := strings.Join(calls, ", "); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeaderMiddlewares(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Request-ID") + " " + r.Header.Get("X-Trace") + " " + r.UserAgent()))
+	}))
+	defer srv.Close()
+
+	var tests = []struct {
+		name string
+		h    func() *Handy
+		want string
+	}{
+		{"request id", func() *Handy {
+			return NewHandy().Use(RequestID(""))
+		}, `^[0-9a-f]{32}  Go-http-client/1.1$`},
+		{"existing request id", func() *Handy {
+			return NewHandy().Use(RequestID("")).Header("x-request-id", "abc")
+		}, `^abc  Go-http-client/1.1$`},
+		{"custom request id header", func() *Handy {
+			return NewHandy().Use(RequestID("X-Trace"))
+		}, `^ [0-9a-f]{32} Go-http-client/1.1$`},
+		{"user agent", func() *Handy {
+			return NewHandy().Use(UserAgent("handy/1.0"))
+		}, `^  handy/1.0$`},
+		{"explicit user agent", func() *Handy {
+			return NewHandy().Use(UserAgent("handy/1.0")).Header("user-agent", "custom")
+		}, `^  custom$`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.h().URL(srv.URL).Get().String()
+			if !regexp.MustCompile(test.want).MatchString(got) {
+				t.Errorf("got %q, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	defer srv.Close()
+
+	var tests = []struct {
+		name string
+		url  string
+		want []string
+	}{
+		{"response", srv.URL + "/tea", []string{
+			"level=INFO", `msg="http request"`, "method=GET", "url=" + srv.URL + "/tea", "status=418", "duration=",
+		}},
+		{"error", closed.URL, []string{
+			"level=ERROR", `msg="http request failed"`, "method=GET", "url=" + closed.URL, "duration=", "error=",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+			NewHandy().URL(test.url).Use(LogRequests(logger)).Get()
+
+			line := buf.String()
+			if strings.Count(line, "\n") != 1 {
+				t.Fatalf("got %q, want one line", line)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(line, want) {
+					t.Errorf("got %q, want it to contain %q", line, want)
+				}
+			}
+		})
+	}
+}
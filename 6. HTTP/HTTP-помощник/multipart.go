@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/textproto"
+	"slices"
+	"strings"
+)
+
+// FilePart описывает файл, который отправляется
+// в теле multipart/form-data запроса
+type FilePart struct {
+	// Field - имя поля формы
+	Field string
+	// Filename - имя файла
+	Filename string
+	// ContentType - тип содержимого, по умолчанию application/octet-stream
+	ContentType string
+	// Reader - источник содержимого файла.
+	// Если он реализует io.Closer, то закрывается после отправки.
+	Reader io.Reader
+}
+
+// Multipart устанавливает текстовые поля и файлы, которые будут
+// закодированы как multipart/form-data и отправлены в теле запроса
+// с соответствующим content-type.
+//
+// Тело не буферизуется в памяти, а передается по мере чтения
+// из файлов, поэтому такой запрос нельзя повторить.
+func (h *Handy) Multipart(fields map[string]string, files ...FilePart) *Handy {
+	h.multipart = &multipartBody{
+		fields:   fields,
+		files:    files,
+		boundary: multipart.NewWriter(nil).Boundary(),
+	}
+	h.body = nil
+	h.headers["Content-Type"] = "multipart/form-data; boundary=" + h.multipart.boundary
+	return h
+}
+
+// multipartBody описывает тело multipart/form-data запроса
+type multipartBody struct {
+	fields   map[string]string
+	files    []FilePart
+	boundary string
+}
+
+// reader возвращает тело запроса, которое формируется
+// в отдельной горутине по мере чтения
+func (m *multipartBody) reader() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(m.write(pw))
+	}()
+	return pr
+}
+
+// write записывает поля и файлы в w
+func (m *multipartBody) write(w io.Writer) error {
+	defer m.closeFiles()
+
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(m.fields)) {
+		if err := writer.WriteField(name, m.fields[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range m.files {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(file.Field), escapeQuotes(file.Filename)))
+		header.Set("Content-Type", contentType)
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, file.Reader); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// closeFiles закрывает источники файлов, которые это поддерживают
+func (m *multipartBody) closeFiles() {
+	for _, file := range m.files {
+		if closer, ok := file.Reader.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes экранирует кавычки в значениях Content-Disposition
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
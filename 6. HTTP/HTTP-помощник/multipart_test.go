@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// closeTracker запоминает, был ли закрыт источник данных
+type closeTracker struct {
+	*strings.Reader
+	closed atomic.Bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// failingReader отдает часть данных, а затем ошибку
+type failingReader struct {
+	err  error
+	done bool
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.done {
+		return 0, f.err
+	}
+	f.done = true
+	return copy(p, "partial"), nil
+}
+
+func TestMultipart(t *testing.T) {
+	// сервер разбирает форму и описывает ее строками
+	// "поле=значение" и "поле: имя файла, тип, содержимое"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var lines []string
+		for name, values := range r.MultipartForm.Value {
+			lines = append(lines, name+"="+strings.Join(values, ","))
+		}
+		for field, headers := range r.MultipartForm.File {
+			for _, header := range headers {
+				file, _ := header.Open()
+				data, _ := io.ReadAll(file)
+				file.Close()
+				lines = append(lines, fmt.Sprintf("%s: %s, %s, %s",
+					field, header.Filename, header.Header.Get("Content-Type"), data))
+			}
+		}
+		slices.Sort(lines)
+		io.WriteString(w, strings.Join(lines, "\n"))
+	}))
+	defer server.Close()
+
+	var tests = []struct {
+		name   string
+		fields map[string]string
+		files  []FilePart
+		want   string
+	}{
+		{"fields", map[string]string{"b": "2", "a": "1"}, nil, "a=1\nb=2"},
+		{"file", nil, []FilePart{
+			{Field: "doc", Filename: "a.txt", ContentType: "text/plain", Reader: strings.NewReader("hello")},
+		}, "doc: a.txt, text/plain, hello"},
+		{"default content type", nil, []FilePart{
+			{Field: "doc", Filename: "a.bin", Reader: strings.NewReader("\x00\x01")},
+		}, "doc: a.bin, application/octet-stream, \x00\x01"},
+		{"quoted filename", nil, []FilePart{
+			{Field: "doc", Filename: `say "hi".txt`, Reader: strings.NewReader("x")},
+		}, `doc: say "hi".txt, application/octet-stream, x`},
+		{"fields and files", map[string]string{"title": "report"}, []FilePart{
+			{Field: "a", Filename: "a.txt", Reader: strings.NewReader("1")},
+			{Field: "b", Filename: "b.txt", Reader: strings.NewReader("2")},
+		}, "a: a.txt, application/octet-stream, 1\nb: b.txt, application/octet-stream, 2\ntitle=report"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := NewHandy().URL(server.URL).Multipart(test.fields, test.files...).Post()
+			if resp.Err() != nil || resp.String() != test.want {
+				t.Errorf("got %q %v, want %q", resp.String(), resp.Err(), test.want)
+			}
+		})
+	}
+
+	t.Run("closes files", func(t *testing.T) {
+		file := &closeTracker{Reader: strings.NewReader("data")}
+		resp := NewHandy().URL(server.URL).
+			Multipart(nil, FilePart{Field: "file", Filename: "data.txt", Reader: file}).Post()
+		if resp.Err() != nil {
+			t.Fatal(resp.Err())
+		}
+		// файл закрывается до конца тела, то есть до ответа сервера
+		if !file.closed.Load() {
+			t.Error("file part was not closed")
+		}
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		errRead := errors.New("read failed")
+		resp := NewHandy().URL(server.URL).
+			Multipart(nil, FilePart{Field: "file", Filename: "data.txt", Reader: &failingReader{err: errRead}}).Post()
+		if !errors.Is(resp.Err(), errRead) {
+			t.Errorf("got %v, want %v", resp.Err(), errRead)
+		}
+	})
+}
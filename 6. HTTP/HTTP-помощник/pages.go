@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrTooManyPages означает, что обход страниц остановлен
+// по ограничению MaxPages, хотя страницы еще оставались
+var ErrTooManyPages = errors.New("too many pages")
+
+// defaultMaxPages ограничивает обход страниц, если MaxPages не задан
+const defaultMaxPages = 1000
+
+// PageStrategy определяет, как запросить первую и следующие страницы
+type PageStrategy interface {
+	// First настраивает запрос первой страницы или возвращает ошибку,
+	// если стратегия настроена неверно
+	First(h *Handy) error
+	// Next настраивает запрос следующей страницы по ответу на текущую
+	// и возвращает false, если страниц больше нет
+	Next(h *Handy, resp *HandyResponse) (bool, error)
+}
+
+// MaxPages ограничивает количество страниц, которые обходит Pages
+func (h *Handy) MaxPages(n int) *Handy {
+	h.maxPages = n
+	return h
+}
+
+// Pages обходит страницы ответа GET-запросами по указанной стратегии.
+// Обход прекращается после последней страницы, при первой ошибке,
+// при отмене контекста или по достижении MaxPages - в последнем случае
+// возвращается ошибка ErrTooManyPages.
+//
+// Страницы запрашиваются на копии h, поэтому h не меняется,
+// а каждый обход начинается с первой страницы.
+func (h *Handy) Pages(strategy PageStrategy) iter.Seq2[*HandyResponse, error] {
+	return func(yield func(*HandyResponse, error) bool) {
+		h := h.Clone()
+		maxPages := h.maxPages
+		if maxPages <= 0 {
+			maxPages = defaultMaxPages
+		}
+
+		if err := strategy.First(h); err != nil {
+			yield(nil, err)
+			return
+		}
+		for range maxPages {
+			if err := h.ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			resp := h.Get()
+			if resp.Err() != nil {
+				yield(resp, resp.Err())
+				return
+			}
+			if !yield(resp, nil) {
+				return
+			}
+
+			more, err := strategy.Next(h, resp)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !more {
+				return
+			}
+		}
+
+		yield(nil, ErrTooManyPages)
+	}
+}
+
+// LinkPages переходит по ссылке rel="next" из заголовка Link (RFC 5988)
+func LinkPages() PageStrategy {
+	return linkPages{}
+}
+
+type linkPages struct{}
+
+func (linkPages) First(h *Handy) error {
+	return nil
+}
+
+func (linkPages) Next(h *Handy, resp *HandyResponse) (bool, error) {
+	next, ok := nextLink(resp.Header.Values("Link"))
+	if !ok {
+		return false, nil
+	}
+
+	current, err := h.resolveURL()
+	if err != nil {
+		return false, err
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return false, err
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return false, err
+	}
+
+	// параметры следующей страницы уже есть в ссылке
+	h.url = base.ResolveReference(ref).String()
+	h.params = &url.Values{}
+	return true, nil
+}
+
+// nextLink ищет ссылку rel="next" в значениях заголовка Link
+func nextLink(values []string) (string, bool) {
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			target, params, _ := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				name, arg, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				// rel может содержать несколько типов через пробел
+				for _, rel := range strings.Fields(strings.Trim(arg, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1], true
+					}
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// CursorPages передает в URL-параметре param курсор, который
+// берется из поля field JSON-ответа. Вложенные поля разделяются
+// точкой: "meta.next_cursor". Пустой курсор означает последнюю страницу.
+func CursorPages(param, field string) PageStrategy {
+	return cursorPages{param, field}
+}
+
+type cursorPages struct {
+	param string
+	field string
+}
+
+func (p cursorPages) First(h *Handy) error {
+	h.params.Del(p.param)
+	return nil
+}
+
+func (p cursorPages) Next(h *Handy, resp *HandyResponse) (bool, error) {
+	value, err := jsonField(resp.Bytes(), p.field)
+	if err != nil {
+		return false, err
+	}
+
+	var cursor string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		cursor = v
+	case json.Number:
+		cursor = v.String()
+	default:
+		return false, fmt.Errorf("cursor field %q is %T, not a string or number", p.field, value)
+	}
+
+	if cursor == "" {
+		return false, nil
+	}
+	h.params.Set(p.param, cursor)
+	return true, nil
+}
+
+// OffsetPages передает смещение и размер страницы в URL-параметрах
+// offsetParam и limitParam. Элементы страницы берутся из поля itemsField
+// JSON-ответа (пустое - ответ целиком является массивом). Страница,
+// в которой меньше limit элементов, считается последней.
+// limit должен быть больше нуля, иначе Pages вернет ошибку.
+func OffsetPages(offsetParam, limitParam string, limit int, itemsField string) PageStrategy {
+	return offsetPages{offsetParam, limitParam, limit, itemsField}
+}
+
+type offsetPages struct {
+	offsetParam string
+	limitParam  string
+	limit       int
+	itemsField  string
+}
+
+func (p offsetPages) First(h *Handy) error {
+	// при нулевом limit любая страница была бы не последней,
+	// а смещение не менялось бы
+	if p.limit <= 0 {
+		return fmt.Errorf("offset pages: limit must be positive, got %d", p.limit)
+	}
+	h.params.Set(p.offsetParam, "0")
+	h.params.Set(p.limitParam, strconv.Itoa(p.limit))
+	return nil
+}
+
+func (p offsetPages) Next(h *Handy, resp *HandyResponse) (bool, error) {
+	value, err := jsonField(resp.Bytes(), p.itemsField)
+	if err != nil {
+		return false, err
+	}
+
+	items, ok := value.([]any)
+	if !ok {
+		return false, fmt.Errorf("items field %q is %T, not an array", p.itemsField, value)
+	}
+	if len(items) < p.limit {
+		return false, nil
+	}
+
+	offset, _ := strconv.Atoi(h.params.Get(p.offsetParam))
+	h.params.Set(p.offsetParam, strconv.Itoa(offset+len(items)))
+	return true, nil
+}
+
+// jsonField возвращает значение поля JSON-документа по пути вида "a.b.c".
+// Пустой путь означает документ целиком, отсутствующее поле - nil.
+func jsonField(data []byte, path string) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		return value, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		value = object[key]
+	}
+	return value, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pagesHandler отдает пять элементов постранично
+// через заголовок Link, курсор и смещение
+func pagesHandler() http.Handler {
+	items := []int{1, 2, 3, 4, 5}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 3 {
+			w.Header().Add("Link", `<https://example.com/first>; rel="first"`)
+			w.Header().Add("Link", fmt.Sprintf(`</link?page=%d>; rel="last next"`, page+1))
+		}
+		fmt.Fprintf(w, "page %d", page)
+	})
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		next := map[string]any{"": "a", "a": 7, "7": ""}[r.URL.Query().Get("cursor")]
+		json.NewEncoder(w).Encode(map[string]any{"meta": map[string]any{"next": next}})
+	})
+	mux.HandleFunc("/offset", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		json.NewEncoder(w).Encode(items[min(offset, len(items)):min(offset+limit, len(items))])
+	})
+
+	return mux
+}
+
+func TestPages(t *testing.T) {
+	server, _ := startServer(t, pagesHandler())
+
+	var tests = []struct {
+		name     string
+		path     string
+		strategy PageStrategy
+		maxPages int
+		want     []string
+		err      error
+	}{
+		{
+			name:     "link",
+			path:     "/link?page=1",
+			strategy: LinkPages(),
+			want:     []string{"page 1", "page 2", "page 3"},
+		},
+		{
+			name:     "cursor",
+			path:     "/cursor",
+			strategy: CursorPages("cursor", "meta.next"),
+			want:     []string{`{"meta":{"next":"a"}}`, `{"meta":{"next":7}}`, `{"meta":{"next":""}}`},
+		},
+		{
+			name:     "offset",
+			path:     "/offset",
+			strategy: OffsetPages("offset", "limit", 2, ""),
+			want:     []string{"[1,2]", "[3,4]", "[5]"},
+		},
+		{
+			name:     "offset full last page",
+			path:     "/offset",
+			strategy: OffsetPages("offset", "limit", 5, ""),
+			want:     []string{"[1,2,3,4,5]", "[]"},
+		},
+		{
+			name:     "max pages",
+			path:     "/link?page=1",
+			strategy: LinkPages(),
+			maxPages: 2,
+			want:     []string{"page 1", "page 2"},
+			err:      ErrTooManyPages,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandy().URL(server.URL + test.path).MaxPages(test.maxPages)
+
+			var got []string
+			var err error
+			for resp, pageErr := range h.Pages(test.strategy) {
+				if pageErr != nil {
+					err = pageErr
+					break
+				}
+				got = append(got, strings.TrimSpace(resp.String()))
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestOffsetPagesLimit(t *testing.T) {
+	server, _ := startServer(t, pagesHandler())
+
+	for _, limit := range []int{0, -1} {
+		h := NewHandy().URL(server.URL + "/offset")
+		pages := 0
+		var err error
+		for _, pageErr := range h.Pages(OffsetPages("offset", "limit", limit, "")) {
+			if pageErr != nil {
+				err = pageErr
+				break
+			}
+			pages++
+		}
+		if pages != 0 || err == nil {
+			t.Errorf("limit %d: got %d pages and error %v, want an error", limit, pages, err)
+		}
+
+		// ошибка стратегии не портит h
+		if resp := h.Get(); resp.Err() != nil {
+			t.Errorf("limit %d: got %v after Pages, want h to stay usable", limit, resp.Err())
+		}
+	}
+}
+
+func TestPagesRepeat(t *testing.T) {
+	server, _ := startServer(t, pagesHandler())
+
+	var tests = []struct {
+		name     string
+		path     string
+		strategy PageStrategy
+		want     string
+	}{
+		{"link", "/link?page=1", LinkPages(), "[page 1 page 2 page 3]"},
+		{"cursor", "/cursor", CursorPages("cursor", "meta.next"), `[{"meta":{"next":"a"}} {"meta":{"next":7}} {"meta":{"next":""}}]`},
+		{"offset", "/offset", OffsetPages("offset", "limit", 2, ""), "[[1,2] [3,4] [5]]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandy().URL(server.URL + test.path)
+			pages := h.Pages(test.strategy)
+
+			// каждый обход начинается с первой страницы
+			for pass := range 2 {
+				var got []string
+				for resp, err := range pages {
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, strings.TrimSpace(resp.String()))
+				}
+				if fmt.Sprint(got) != test.want {
+					t.Errorf("pass %d: got %v, want %s", pass+1, got, test.want)
+				}
+			}
+
+			if h.url != server.URL+test.path || len(*h.params) != 0 {
+				t.Errorf("got url %q and params %v, want h unchanged", h.url, *h.params)
+			}
+		})
+	}
+}
+
+func TestPagesCancel(t *testing.T) {
+	server, _ := startServer(t, pagesHandler())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pages := 0
+	var err error
+	for _, pageErr := range NewHandy().URL(server.URL + "/link?page=1").Context(ctx).Pages(LinkPages()) {
+		if pageErr != nil {
+			err = pageErr
+			break
+		}
+		pages++
+		cancel()
+	}
+
+	if pages != 1 || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %d pages and error %v, want 1 page and context.Canceled", pages, err)
+	}
+}
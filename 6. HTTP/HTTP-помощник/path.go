@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// pathParamRe находит подстановки вида {name} в шаблоне URL
+var pathParamRe = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// BaseURL устанавливает базовый адрес, к которому добавляется
+// относительный URL запроса. Абсолютный URL базовый адрес не меняет.
+func (h *Handy) BaseURL(base string) *Handy {
+	h.baseURL = base
+	return h
+}
+
+// PathParam устанавливает значение подстановки {name} в URL.
+// Значение экранируется через url.PathEscape. Если в URL осталась
+// подстановка без значения, запрос не отправляется, а ответ
+// содержит ошибку.
+func (h *Handy) PathParam(name, value string) *Handy {
+	h.pathParams[name] = value
+	return h
+}
+
+// resolveURL подставляет значения в шаблон URL
+// и добавляет его к базовому адресу
+func (h *Handy) resolveURL() (string, error) {
+	var unresolved []string
+	uri := pathParamRe.ReplaceAllStringFunc(h.url, func(match string) string {
+		name := match[1 : len(match)-1]
+		value, ok := h.pathParams[name]
+		if !ok {
+			unresolved = append(unresolved, name)
+			return match
+		}
+		return url.PathEscape(value)
+	})
+
+	if len(unresolved) > 0 {
+		return "", fmt.Errorf("unresolved path parameters in %q: %s", h.url, strings.Join(unresolved, ", "))
+	}
+
+	if h.baseURL == "" {
+		return uri, nil
+	}
+	if u, err := url.Parse(uri); err == nil && u.IsAbs() {
+		return uri, nil
+	}
+	if uri == "" {
+		return h.baseURL, nil
+	}
+	return strings.TrimRight(h.baseURL, "/") + "/" + strings.TrimLeft(uri, "/"), nil
+}
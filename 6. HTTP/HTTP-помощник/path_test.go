@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveURL(t *testing.T) {
+	var tests = []struct {
+		name   string
+		base   string
+		url    string
+		params map[string]string
+		want   string
+	}{
+		{"no base", "", "https://example.com/items", nil, "https://example.com/items"},
+		{"relative", "https://example.com/api", "items", nil, "https://example.com/api/items"},
+		{"slashes", "https://example.com/api/", "/items", nil, "https://example.com/api/items"},
+		{"absolute", "https://example.com/api", "https://other.com/items", nil, "https://other.com/items"},
+		{"empty url", "https://example.com/api", "", nil, "https://example.com/api"},
+		{"path param", "https://example.com", "/users/{id}/items/{item}",
+			map[string]string{"id": "42", "item": "a b"}, "https://example.com/users/42/items/a%20b"},
+		{"escaped slash", "https://example.com", "/files/{name}",
+			map[string]string{"name": "a/b"}, "https://example.com/files/a%2Fb"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandy().BaseURL(test.base).URL(test.url)
+			for name, value := range test.params {
+				h.PathParam(name, value)
+			}
+			got, err := h.resolveURL()
+			if err != nil || got != test.want {
+				t.Errorf("got %q %v, want %q", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestPathParamRequest(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+	}))
+	defer server.Close()
+
+	// с URL-параметрами URL разбирается и собирается заново,
+	// но экранированный слеш должен сохраниться
+	for _, param := range []string{"", "1"} {
+		h := NewHandy().BaseURL(server.URL).URL("/files/{name}").PathParam("name", "a/b")
+		if param != "" {
+			h.Param("v", param)
+		}
+		if resp := h.Get(); resp.Err() != nil {
+			t.Fatal(resp.Err())
+		}
+		if path != "/files/a%2Fb" {
+			t.Errorf("param %q: got path %q, want %q", param, path, "/files/a%2Fb")
+		}
+	}
+}
+
+func TestUnresolvedPathParam(t *testing.T) {
+	server, hits := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	resp := NewHandy().BaseURL(server.URL).URL("/users/{id}/items/{item}").PathParam("id", "42").Get()
+	if err := resp.Err(); err == nil || !strings.HasSuffix(err.Error(), ": item") {
+		t.Errorf("got %v, want unresolved item", err)
+	}
+	if got := hits.Load(); got != 0 {
+		t.Errorf("hits: got %d, want 0", got)
+	}
+}
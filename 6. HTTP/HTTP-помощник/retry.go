@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy описывает, когда и как повторять неудачные запросы
+type RetryPolicy struct {
+	// MaxAttempts - максимальное количество попыток, включая первую
+	MaxAttempts int
+	// BaseDelay - задержка перед первым повтором,
+	// каждый следующий повтор ждет вдвое дольше
+	BaseDelay time.Duration
+	// MaxDelay ограничивает задержку между попытками,
+	// в том числе заданную сервером через Retry-After.
+	// Ноль означает, что задержка не ограничена.
+	MaxDelay time.Duration
+	// RetryStatuses - коды HTTP-статусов, при которых запрос повторяется
+	RetryStatuses []int
+	// RetryError решает, повторять ли запрос после ошибки транспорта.
+	// Если не задана, повторяются все ошибки, кроме отмены контекста
+	// и разомкнутой цепи предохранителя.
+	RetryError func(err error) bool
+	// NonIdempotent разрешает повторять POST- и PATCH-запросы
+	NonIdempotent bool
+}
+
+// DefaultRetryPolicy возвращает политику повторов по умолчанию:
+// три попытки с экспоненциальной задержкой от 100 мс до 5 с
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		RetryStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// Retry включает повтор неудачных запросов по указанной политике
+func (h *Handy) Retry(policy RetryPolicy) *Handy {
+	h.retry = &policy
+	return h
+}
+
+// retryable проверяет, нужно ли повторить запрос
+// после attempt-й попытки с результатом resp, err
+func (p *RetryPolicy) retryable(method string, attempt int, resp *http.Response, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+
+	if !p.NonIdempotent && !isIdempotent(method) {
+		return false
+	}
+
+	if err != nil {
+		if p.RetryError != nil {
+			return p.RetryError(err)
+		}
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrCircuitOpen)
+	}
+
+	return slices.Contains(p.RetryStatuses, resp.StatusCode)
+}
+
+// backoff возвращает задержку перед следующей попыткой.
+// Если сервер прислал Retry-After на 429 или 503, используется он.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return p.capDelay(delay)
+		}
+	}
+
+	delay := p.BaseDelay << (attempt - 1)
+	if delay < p.BaseDelay {
+		// переполнение при большом количестве попыток
+		delay = time.Duration(math.MaxInt64)
+	}
+	delay = p.capDelay(delay)
+	if delay <= 0 {
+		return 0
+	}
+
+	// половина задержки фиксированная, половина случайная,
+	// чтобы клиенты не повторяли запросы одновременно
+	half := delay / 2
+	return half + rand.N(delay-half)
+}
+
+// capDelay ограничивает задержку значением MaxDelay, если оно задано
+func (p *RetryPolicy) capDelay(delay time.Duration) time.Duration {
+	if p.MaxDelay > 0 {
+		return min(delay, p.MaxDelay)
+	}
+	return delay
+}
+
+// parseRetryAfter разбирает значение заголовка Retry-After,
+// которое может быть количеством секунд или HTTP-датой
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(date), 0), true
+}
+
+// sleep ждет указанное время или отмены контекста
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isIdempotent проверяет, можно ли безопасно повторить запрос
+// указанным методом
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// replayable проверяет, можно ли отправить запрос повторно:
+// тело запроса должно либо отсутствовать, либо создаваться заново
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// discard дочитывает и закрывает тело ответа,
+// чтобы соединение можно было переиспользовать
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var tests = []struct {
+		name     string
+		method   string
+		policy   func(p *RetryPolicy)
+		failures int32
+		status   int
+		attempts int
+	}{
+		{"get recovers", http.MethodGet, nil, 2, http.StatusOK, 3},
+		{"get gives up", http.MethodGet, nil, 5, http.StatusServiceUnavailable, 3},
+		{"post not retried", http.MethodPost, nil, 1, http.StatusServiceUnavailable, 1},
+		{"post opted in", http.MethodPost, func(p *RetryPolicy) { p.NonIdempotent = true }, 1, http.StatusOK, 2},
+		{"status not retryable", http.MethodGet, func(p *RetryPolicy) { p.RetryStatuses = nil }, 1, http.StatusServiceUnavailable, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= test.failures {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			policy := DefaultRetryPolicy()
+			policy.BaseDelay = time.Millisecond
+			if test.policy != nil {
+				test.policy(&policy)
+			}
+
+			resp := NewHandy().URL(server.URL).Retry(policy).Do(test.method)
+			if resp.StatusCode != test.status {
+				t.Errorf("status: got %d, want %d", resp.StatusCode, test.status)
+			}
+			if resp.Attempts != test.attempts {
+				t.Errorf("attempts: got %d, want %d", resp.Attempts, test.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryTransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	uri := server.URL
+	server.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+
+	resp := NewHandy().URL(uri).Retry(policy).Get()
+	if resp.Err() == nil {
+		t.Fatal("expected error")
+	}
+	if resp.Attempts != policy.MaxAttempts {
+		t.Errorf("attempts: got %d, want %d", resp.Attempts, policy.MaxAttempts)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	var tests = []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, true},
+		{"soon", 0, false},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(test.value)
+			if got != test.want || ok != test.ok {
+				t.Errorf("got (%v, %v), want (%v, %v)", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	retryAfter := func(status int, value string) *http.Response {
+		return &http.Response{StatusCode: status, Header: http.Header{"Retry-After": {value}}}
+	}
+
+	var tests = []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		resp    *http.Response
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"retry-after without max delay", RetryPolicy{BaseDelay: time.Second},
+			1, retryAfter(http.StatusTooManyRequests, "2"), 2 * time.Second, 2 * time.Second},
+		{"retry-after capped", RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Second},
+			1, retryAfter(http.StatusServiceUnavailable, "2"), time.Second, time.Second},
+		{"exponential without max delay", RetryPolicy{BaseDelay: time.Second},
+			3, nil, 2 * time.Second, 4 * time.Second},
+		{"exponential capped", RetryPolicy{BaseDelay: time.Second, MaxDelay: 3 * time.Second},
+			3, nil, 1500 * time.Millisecond, 3 * time.Second},
+		{"many attempts", RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute},
+			100, nil, 30 * time.Second, time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.policy.backoff(test.attempt, test.resp)
+			if got < test.min || got > test.max {
+				t.Errorf("got %v, want between %v and %v", got, test.min, test.max)
+			}
+		})
+	}
+}
+
+func TestRetryAfterWithoutMaxDelay(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{
+		MaxAttempts:   5,
+		BaseDelay:     time.Millisecond,
+		RetryStatuses: []int{http.StatusTooManyRequests},
+	}
+
+	start := time.Now()
+	resp := NewHandy().URL(server.URL).Retry(policy).Get()
+	if resp.Err() != nil {
+		t.Fatal(resp.Err())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("got retry after %v, want Retry-After of 1s", elapsed)
+	}
+}
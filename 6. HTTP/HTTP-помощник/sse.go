@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultEventsRetry - задержка перед переподключением к потоку событий,
+// пока сервер не прислал свою в поле retry
+const defaultEventsRetry = 3 * time.Second
+
+// Event - событие из потока Server-Sent Events
+type Event struct {
+	// ID - идентификатор последнего события в потоке
+	ID string
+	// Event - тип события, по умолчанию "message"
+	Event string
+	// Data - данные события, строки разделены символом \n
+	Data string
+	// Retry - задержка переподключения, если сервер передал ее с событием
+	Retry time.Duration
+}
+
+// Events подключается к потоку Server-Sent Events (text/event-stream)
+// и возвращает последовательность событий из него.
+//
+// Когда сервер закрывает поток, Events переподключается с заголовком
+// Last-Event-ID через задержку, которую задал сервер (по умолчанию 3 с).
+// Если соединение обрывается с ошибкой, Events сначала возвращает ее,
+// а переподключается, только если обход продолжается. Ответ с HTTP-статусом,
+// отличным от 2xx, или неверным типом содержимого, отмена ctx
+// и ответ 204 No Content завершают обход.
+//
+// Timeout ограничивает каждое подключение целиком, поэтому
+// для потоков событий его обычно не задают.
+func (h *Handy) Events(ctx context.Context) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		stream := eventStream{retry: defaultEventsRetry}
+
+		for {
+			resp := stream.connect(ctx, h)
+			if resp.StatusCode == http.StatusNoContent {
+				resp.Close()
+				return
+			}
+
+			err := resp.Err()
+			if err == nil {
+				err = stream.read(resp.Body, yield)
+			}
+			resp.Close()
+			if errors.Is(err, errStopEvents) {
+				return
+			}
+
+			if ctx.Err() != nil {
+				yield(Event{}, ctx.Err())
+				return
+			}
+
+			var statusErr StatusErr
+			if errors.As(err, &statusErr) || errors.Is(err, errNotEventStream) {
+				yield(Event{}, err)
+				return
+			}
+
+			// сервер закрыл поток - просто переподключаемся
+			if !errors.Is(err, io.EOF) && !yield(Event{}, err) {
+				return
+			}
+			if err := sleep(ctx, stream.retry); err != nil {
+				yield(Event{}, err)
+				return
+			}
+		}
+	}
+}
+
+var (
+	// errStopEvents означает, что обход событий прерван
+	errStopEvents = errors.New("stop events")
+	// errNotEventStream означает, что сервер ответил не потоком событий
+	errNotEventStream = errors.New("response is not text/event-stream")
+)
+
+// eventStream хранит состояние потока событий между подключениями
+type eventStream struct {
+	lastID string
+	retry  time.Duration
+}
+
+// connect открывает поток событий.
+// Запрос выполняется на копии h, чтобы не менять ее заголовки.
+func (s *eventStream) connect(ctx context.Context, h *Handy) *HandyResponse {
+	req := h.Clone().Stream().
+		Header("Accept", "text/event-stream").
+		Header("Cache-Control", "no-cache")
+	if s.lastID != "" {
+		req.Header("Last-Event-ID", s.lastID)
+	}
+
+	resp := req.DoContext(ctx, http.MethodGet)
+	if resp.Err() != nil || resp.StatusCode == http.StatusNoContent {
+		return resp
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		resp.error = fmt.Errorf("%w: %s", errNotEventStream, resp.Header.Get("Content-Type"))
+	}
+	return resp
+}
+
+// read разбирает события из тела ответа и передает их в yield.
+// Возвращает errStopEvents, если yield прервал обход.
+func (s *eventStream) read(body io.Reader, yield func(Event, error) bool) error {
+	reader := bufio.NewReader(body)
+
+	var event Event
+	var data strings.Builder
+	hasData := false
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// незавершенное событие в конце потока отбрасывается
+			return err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		// пустая строка завершает событие
+		if line == "" {
+			if hasData {
+				event.ID = s.lastID
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				event.Data = strings.TrimSuffix(data.String(), "\n")
+				if !yield(event, nil) {
+					return errStopEvents
+				}
+			}
+			event = Event{}
+			data.Reset()
+			hasData = false
+			continue
+		}
+
+		// комментарий
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+				event.Retry = s.retry
+			}
+		}
+	}
+}
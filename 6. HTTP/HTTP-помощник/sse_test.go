@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	var connections atomic.Int32
+	var lastEventID atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if connections.Add(1) == 1 {
+			io.WriteString(w, ": comment\n\n"+
+				"retry: 10\n"+
+				"id: 1\ndata: first\n\n"+
+				"event: update\nid: 2\ndata: line 1\r\ndata: line 2\r\n\r\n"+
+				"data: unfinished")
+			return
+		}
+		lastEventID.Store(r.Header.Get("Last-Event-ID"))
+		io.WriteString(w, "data: {\"n\":3}\n\n")
+	}))
+	defer srv.Close()
+
+	var got []Event
+	start := time.Now()
+	for event, err := range NewHandy().URL(srv.URL).Events(t.Context()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, event)
+		if len(got) == 3 {
+			break
+		}
+	}
+
+	want := []Event{
+		{ID: "1", Event: "message", Data: "first", Retry: 10 * time.Millisecond},
+		{ID: "2", Event: "update", Data: "line 1\nline 2"},
+		{ID: "2", Event: "message", Data: `{"n":3}`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if id := lastEventID.Load(); id != "2" {
+		t.Errorf("Last-Event-ID: got %v, want 2", id)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("reconnect took %v, want server retry of 10ms", elapsed)
+	}
+}
+
+func TestEventsErrors(t *testing.T) {
+	var tests = []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"status", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "gone", http.StatusGone)
+		}, "410"},
+		{"content type", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "data: x\n\n")
+		}, "not text/event-stream"},
+		{"no content", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(test.handler)
+			defer srv.Close()
+
+			var errs []error
+			for _, err := range NewHandy().URL(srv.URL).Events(t.Context()) {
+				errs = append(errs, err)
+			}
+
+			got := errors.Join(errs...)
+			if test.want == "" {
+				if got != nil {
+					t.Errorf("got %v, want no error", got)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(got.Error(), test.want) {
+				t.Errorf("got %v, want single error %q", got, test.want)
+			}
+		})
+	}
+}
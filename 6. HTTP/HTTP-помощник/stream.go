@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+)
+
+// ErrBodyTooLarge означает, что тело ответа
+// превышает размер, заданный через MaxBodySize
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// Stream включает потоковое чтение ответа: тело не загружается
+// в память целиком, а доступно через HandyResponse.Body,
+// Lines или DecodeEach. Тело нужно закрыть после чтения.
+func (h *Handy) Stream() *Handy {
+	h.stream = true
+	return h
+}
+
+// MaxBodySize ограничивает размер тела ответа в байтах.
+// Если ответ больше, чтение завершается ошибкой ErrBodyTooLarge.
+func (h *Handy) MaxBodySize(n int64) *Handy {
+	h.maxBodySize = n
+	return h
+}
+
+// Close закрывает тело ответа при потоковом чтении
+func (r *HandyResponse) Close() error {
+	if r.Body == nil {
+		return nil
+	}
+	return r.Body.Close()
+}
+
+// Lines возвращает последовательность строк тела ответа
+// без завершающих символов перевода строки.
+// Если при чтении произошла ошибка, она доступна через r.Err().
+func (r *HandyResponse) Lines() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		body := r.reader()
+		defer body.Close()
+
+		reader := bufio.NewReader(body)
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 {
+				line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+				if !yield(line) {
+					return
+				}
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				r.error = err
+				return
+			}
+		}
+	}
+}
+
+// DecodeEach декодирует тело ответа как последовательность
+// JSON-значений (например, NDJSON) и вызывает fn для каждого.
+// Ошибка декодирования или ошибка из fn прерывает чтение
+// и становится доступна через r.Err().
+func DecodeEach[T any](r *HandyResponse, fn func(v T) error) {
+	body := r.reader()
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		var v T
+		err := decoder.Decode(&v)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			r.error = err
+			return
+		}
+
+		if err := fn(v); err != nil {
+			r.error = err
+			return
+		}
+	}
+}
+
+// reader возвращает тело ответа для последовательного чтения
+func (r *HandyResponse) reader() io.ReadCloser {
+	if r.Body != nil {
+		return r.Body
+	}
+	return io.NopCloser(bytes.NewReader(r.ResponseBody))
+}
+
+// load дочитывает тело ответа при потоковом чтении,
+// чтобы с ним можно было работать как с обычным ответом
+func (r *HandyResponse) load() {
+	if r.Body == nil {
+		return
+	}
+
+	body := r.Body
+	r.Body = nil
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	r.ResponseBody = data
+	if err != nil {
+		r.error = err
+	}
+}
+
+// limitBody возвращает тело ответа, размер которого
+// ограничен limit байтами. Если limit не больше нуля,
+// тело возвращается без ограничений.
+func limitBody(resp *http.Response, limit int64) (io.ReadCloser, error) {
+	if limit <= 0 {
+		return resp.Body, nil
+	}
+
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		return nil, ErrBodyTooLarge
+	}
+	return &limitedBody{resp.Body, limit}, nil
+}
+
+// limitedBody возвращает ErrBodyTooLarge,
+// если из тела прочитано больше n байт
+type limitedBody struct {
+	body io.ReadCloser
+	n    int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// читаем на байт больше остатка, чтобы заметить превышение
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+
+	n, err := b.body.Read(p)
+	if int64(n) > b.n {
+		n = int(b.n)
+		b.n = 0
+		return n, ErrBodyTooLarge
+	}
+
+	b.n -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// readCloser объединяет источник данных и функцию закрытия
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// streamBody отменяет контекст запроса при закрытии тела
+type streamBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *streamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first\r\nsecond\n\nlast")
+	}))
+	defer server.Close()
+
+	want := []string{"first", "second", "", "last"}
+	for _, stream := range []bool{false, true} {
+		t.Run("stream "+strconv.FormatBool(stream), func(t *testing.T) {
+			h := NewHandy().URL(server.URL)
+			if stream {
+				h.Stream()
+			}
+			resp := h.Get()
+			defer resp.Close()
+
+			got := slices.Collect(resp.Lines())
+			if !slices.Equal(got, want) || resp.Err() != nil {
+				t.Errorf("got %q %v, want %q", got, resp.Err(), want)
+			}
+		})
+	}
+}
+
+func TestDecodeEach(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n")
+		if r.URL.Query().Has("broken") {
+			io.WriteString(w, "{\"n\":")
+		}
+	}))
+	defer server.Close()
+
+	type item struct {
+		N int `json:"n"`
+	}
+	errStop := errors.New("stop")
+
+	var tests = []struct {
+		name   string
+		broken bool
+		stopAt int
+		want   []int
+		err    bool
+	}{
+		{"all", false, 0, []int{1, 2, 3}, false},
+		{"fn error", false, 2, []int{1, 2}, true},
+		{"broken json", true, 0, []int{1, 2, 3}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandy().URL(server.URL).Stream()
+			if test.broken {
+				h.Param("broken", "1")
+			}
+			resp := h.Get()
+			defer resp.Close()
+
+			var got []int
+			DecodeEach(resp, func(v item) error {
+				got = append(got, v.N)
+				if v.N == test.stopAt {
+					return errStop
+				}
+				return nil
+			})
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if (resp.Err() != nil) != test.err {
+				t.Errorf("got error %v, want error %v", resp.Err(), test.err)
+			}
+			if test.stopAt > 0 && !errors.Is(resp.Err(), errStop) {
+				t.Errorf("got %v, want error from fn", resp.Err())
+			}
+		})
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		if r.URL.Query().Has("chunked") {
+			// без Content-Length размер известен только при чтении
+			w.(http.Flusher).Flush()
+		}
+		io.WriteString(w, strings.Repeat("x", size))
+	}))
+	defer server.Close()
+
+	const limit = 10
+
+	var tests = []struct {
+		size    int
+		chunked bool
+		stream  bool
+		err     error
+	}{
+		{limit - 1, false, false, nil},
+		{limit, false, false, nil},
+		{limit + 1, false, false, ErrBodyTooLarge},
+		{limit, true, false, nil},
+		{limit + 1, true, false, ErrBodyTooLarge},
+		{limit, true, true, nil},
+		{limit + 1, true, true, ErrBodyTooLarge},
+	}
+
+	for _, test := range tests {
+		name := fmt.Sprintf("size %d chunked %v stream %v", test.size, test.chunked, test.stream)
+		t.Run(name, func(t *testing.T) {
+			h := NewHandy().URL(server.URL).Param("size", strconv.Itoa(test.size)).MaxBodySize(limit)
+			if test.chunked {
+				h.Param("chunked", "1")
+			}
+			if test.stream {
+				h.Stream()
+			}
+
+			resp := h.Get()
+			err := resp.Err()
+			var body []byte
+			if test.stream && err == nil {
+				body, err = io.ReadAll(resp.Body)
+				resp.Close()
+			} else {
+				body = resp.Bytes()
+			}
+
+			if !errors.Is(err, test.err) {
+				t.Errorf("got %v, want %v", err, test.err)
+			}
+			if test.err == nil && len(body) != test.size {
+				t.Errorf("got %d bytes, want %d", len(body), test.size)
+			}
+		})
+	}
+}
+
+func TestStreamStatusErr(t *testing.T) {
+	// тело длиннее maxErrorBody, чтобы в ошибку попало только его начало
+	body := strings.Repeat("e", maxErrorBody+100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	resp := NewHandy().URL(server.URL).Stream().Get()
+	defer resp.Close()
+
+	var statusErr StatusErr
+	if !errors.As(resp.Err(), &statusErr) || statusErr.Code != http.StatusBadGateway {
+		t.Fatalf("got %v, want StatusErr 502", resp.Err())
+	}
+	if len(statusErr.Body) != maxErrorBody {
+		t.Errorf("error body: got %d bytes, want %d", len(statusErr.Body), maxErrorBody)
+	}
+
+	// начало тела, прочитанное для ошибки, остается в потоке
+	got, err := io.ReadAll(resp.Body)
+	if err != nil || string(got) != body {
+		t.Errorf("got %d bytes %v, want %d", len(got), err, len(body))
+	}
+}
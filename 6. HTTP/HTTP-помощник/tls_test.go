@@ -0,0 +1,239 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert - сертификат и ключ в PEM
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert выпускает сертификат, подписанный parent,
+// или самоподписанный CA, если parent == nil
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func TestTLS(t *testing.T) {
+	ca := newTestCert(t, "Test CA", nil)
+	server := newTestCert(t, "server", ca)
+	client := newTestCert(t, "client", ca)
+	other := newTestCert(t, "Other CA", nil)
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	var tests = []struct {
+		name    string
+		config  TLSConfig
+		wantErr string
+	}{
+		{"unknown CA", TLSConfig{ClientCert: client.certPEM, ClientKey: client.keyPEM}, "unknown authority"},
+		{"no client cert", TLSConfig{RootCAs: ca.certPEM}, "certificate"},
+		{"mTLS", TLSConfig{RootCAs: ca.certPEM, ClientCert: client.certPEM, ClientKey: client.keyPEM}, ""},
+		{"pinned CA", TLSConfig{
+			RootCAs: ca.certPEM, ClientCert: client.certPEM, ClientKey: client.keyPEM,
+			Pins: []string{SPKIHash(ca.cert)},
+		}, ""},
+		{"pin mismatch", TLSConfig{
+			RootCAs: ca.certPEM, ClientCert: client.certPEM, ClientKey: client.keyPEM,
+			Pins: []string{SPKIHash(other.cert)},
+		}, SPKIHash(server.cert) + " (CN=server)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			httpClient, err := NewTLSClient(test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer httpClient.CloseIdleConnections()
+
+			resp := NewHandy().Client(httpClient).URL(srv.URL).Get()
+
+			if test.wantErr == "" {
+				if resp.Err() != nil {
+					t.Fatal(resp.Err())
+				}
+				if got := resp.String(); got != "client" {
+					t.Errorf("got %q, want %q", got, "client")
+				}
+				return
+			}
+
+			if resp.Err() == nil || !strings.Contains(resp.Err().Error(), test.wantErr) {
+				t.Errorf("got error %v, want %q", resp.Err(), test.wantErr)
+			}
+			if test.config.Pins != nil && !errors.Is(resp.Err(), ErrPinMismatch) {
+				t.Errorf("got error %v, want ErrPinMismatch", resp.Err())
+			}
+		})
+	}
+}
+
+func TestTLSClientReuse(t *testing.T) {
+	ca := newTestCert(t, "Test CA", nil)
+	server := newTestCert(t, "server", ca)
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	httpClient, err := NewTLSClient(TLSConfig{RootCAs: ca.certPEM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpClient.CloseIdleConnections()
+
+	// запросы через общий клиент используют одно соединение
+	for i := range 3 {
+		resp := NewHandy().Client(httpClient).URL(srv.URL).Trace().Get()
+		if resp.Err() != nil {
+			t.Fatal(resp.Err())
+		}
+		if got := resp.Timings.ConnReused; got != (i > 0) {
+			t.Errorf("request %d: got reused %v, want %v", i, got, i > 0)
+		}
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	ca := newTestCert(t, "Test CA", nil)
+	client := newTestCert(t, "client", ca)
+	other := newTestCert(t, "Other CA", nil)
+
+	var tests = []struct {
+		name    string
+		config  TLSConfig
+		wantErr string
+	}{
+		{"bad CA PEM", TLSConfig{RootCAs: []byte("not a certificate")}, "no certificates found"},
+		{"bad client key", TLSConfig{ClientCert: client.certPEM, ClientKey: other.keyPEM}, "client certificate"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTLSClient(test.config)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestPinUnverifiedCert(t *testing.T) {
+	ca := newTestCert(t, "Test CA", nil)
+	server := newTestCert(t, "server", ca)
+	pinned := newTestCert(t, "Pinned CA", nil)
+
+	// сервер дописывает в цепочку чужой сертификат с закрепленным ключом
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCert.Certificate = append(serverCert.Certificate, pinned.cert.Raw)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "pwned")
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	httpClient, err := NewTLSClient(TLSConfig{RootCAs: ca.certPEM, Pins: []string{SPKIHash(pinned.cert)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpClient.CloseIdleConnections()
+
+	resp := NewHandy().Client(httpClient).URL(srv.URL).Get()
+	if !errors.Is(resp.Err(), ErrPinMismatch) {
+		t.Errorf("got %q, %v, want ErrPinMismatch", resp.String(), resp.Err())
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Trace включает замер времени этапов запроса:
+// результат доступен в HandyResponse.Timings
+func (h *Handy) Trace() *Handy {
+	h.trace = true
+	return h
+}
+
+// Timings описывает, сколько времени заняли этапы запроса.
+// При повторах все значения, кроме Total, относятся к последней попытке.
+type Timings struct {
+	// DNS - поиск IP-адреса хоста
+	DNS time.Duration
+	// Connect - установка TCP-соединения
+	Connect time.Duration
+	// TLS - TLS-рукопожатие
+	TLS time.Duration
+	// FirstByte - время от начала попытки до первого байта ответа
+	FirstByte time.Duration
+	// Total - время всего запроса вместе с повторами и чтением тела.
+	// При потоковом чтении - до получения заголовков ответа.
+	Total time.Duration
+	// ConnReused - соединение взято из пула, поэтому
+	// DNS, Connect и TLS не понадобились
+	ConnReused bool
+}
+
+// tracer собирает Timings из событий httptrace.
+// События приходят из разных горутин транспорта.
+type tracer struct {
+	mu      sync.Mutex
+	start   time.Time
+	attempt time.Time
+	dns     time.Time
+	connect time.Time
+	tls     time.Time
+	timings Timings
+}
+
+func newTracer() *tracer {
+	return &tracer{start: time.Now()}
+}
+
+// withContext добавляет трассировку запроса в контекст.
+// Если трассировка не включена, контекст не меняется.
+func (t *tracer) withContext(ctx context.Context) context.Context {
+	if t == nil {
+		return ctx
+	}
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			// каждая попытка начинается с получения соединения
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.attempt = time.Now()
+			t.timings = Timings{}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.ConnReused = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.DNS = time.Since(t.dns)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connect = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.timings.Connect = time.Since(t.connect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tls = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.TLS = time.Since(t.tls)
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.FirstByte = time.Since(t.attempt)
+		},
+	})
+}
+
+// result возвращает собранные значения
+// или nil, если трассировка не включена
+func (t *tracer) result() *Timings {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := t.timings
+	timings.Total = time.Since(t.start)
+	return &timings
+}
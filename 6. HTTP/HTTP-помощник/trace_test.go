@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrace(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	h := NewHandy().Client(srv.Client()).URL(srv.URL).Trace()
+
+	first := h.Get()
+	if first.Err() != nil {
+		t.Fatal(first.Err())
+	}
+	timings := first.Timings
+	if timings.ConnReused {
+		t.Error("first request: got reused connection, want new")
+	}
+	if timings.Connect <= 0 || timings.TLS <= 0 {
+		t.Errorf("first request: got connect %v, tls %v, want > 0", timings.Connect, timings.TLS)
+	}
+	if timings.FirstByte < 20*time.Millisecond {
+		t.Errorf("first request: got first byte %v, want >= 20ms", timings.FirstByte)
+	}
+	if timings.Total < timings.FirstByte {
+		t.Errorf("first request: got total %v < first byte %v", timings.Total, timings.FirstByte)
+	}
+
+	second := h.Get().Timings
+	if !second.ConnReused {
+		t.Error("second request: got new connection, want reused")
+	}
+	if second.Connect != 0 || second.TLS != 0 {
+		t.Errorf("second request: got connect %v, tls %v, want 0", second.Connect, second.TLS)
+	}
+
+	if got := NewHandy().Client(srv.Client()).URL(srv.URL).Get().Timings; got != nil {
+		t.Errorf("without trace: got %+v, want nil", got)
+	}
+}